@@ -0,0 +1,46 @@
+// Package v1 contains the typed API for the core.kops.aks.microsoft.com/v1
+// group: Operation, the record of one in-flight or completed Azure
+// operation against an AKS cluster or agent pool.
+package v1
+
+import (
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// GroupVersion is the API group and version this package's types belong to.
+var GroupVersion = schema.GroupVersion{Group: "core.kops.aks.microsoft.com", Version: "v1"}
+
+var (
+	// SchemeBuilder collects this package's types so AddToScheme can
+	// register all of them in one call.
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+
+	// AddToScheme adds every type in this package to a runtime.Scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion,
+		&Operation{},
+		&OperationList{},
+	)
+	metav1.AddToGroupVersion(scheme, GroupVersion)
+	return nil
+}
+
+// Scheme is the package-level scheme every reconciler and test in this
+// repo shares, so a client built against it understands both Operation and
+// the built-in types (ConfigMaps, Events, ...) reconcilers also touch.
+// Building a fresh *runtime.Scheme per caller risks one of them forgetting
+// a registration the others rely on.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(Scheme))
+	utilruntime.Must(AddToScheme(Scheme))
+}
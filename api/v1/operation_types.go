@@ -0,0 +1,104 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types an Operation's status.conditions can carry.
+const (
+	ConditionReady      = "Ready"
+	ConditionInProgress = "InProgress"
+	ConditionFailed     = "Failed"
+	// ConditionPreflightSucceeded reflects whether the reconciler's
+	// preflight checks (credential validity, ARM/cluster reachability,
+	// RBAC) passed on the most recent sync attempt.
+	ConditionPreflightSucceeded = "PreflightSucceeded"
+)
+
+// Phase values an Operation moves through. Unlike the unstructured CRs this
+// type replaces, Operations are never deleted on completion - they settle
+// into one of the terminal phases below and stick around until
+// HistoryRetention (or spec.ttlSecondsAfterFinished) reaps them.
+const (
+	PhaseInProgress = "InProgress"
+	PhaseSucceeded  = "Succeeded"
+	PhaseFailed     = "Failed"
+	PhaseCanceled   = "Canceled"
+)
+
+// OperationSpec is immutable once an Operation is created: it records what
+// Azure operation this CR is tracking, not its current state.
+type OperationSpec struct {
+	// ClusterName is the AKS cluster the operation ran (or is running)
+	// against.
+	ClusterName string `json:"clusterName"`
+	// ResourceGroup is the Azure resource group ClusterName lives in.
+	ResourceGroup string `json:"resourceGroup"`
+	// AgentPoolName is set when the operation is scoped to one agent pool
+	// rather than the cluster as a whole.
+	AgentPoolName string `json:"agentPoolName,omitempty"`
+	// OperationType is Azure's name for the kind of operation, e.g.
+	// "Upgrading" or "Scaling".
+	OperationType string `json:"operationType"`
+	// OperationID is Azure's identifier for this specific operation
+	// instance, hashed into the Operation's name so a new operation of the
+	// same type never collides with (and resurrects) a completed one.
+	OperationID string `json:"operationID"`
+	// TTLSecondsAfterFinished overrides the reconciler's default
+	// HistoryRetention for how long this Operation is kept around once it
+	// reaches a terminal phase. Unset means the default applies.
+	TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// OperationStatus is what the reconciler patches via the status
+// subresource as it observes Azure's side of the operation.
+type OperationStatus struct {
+	// Phase is the Operation's current lifecycle phase; see the Phase*
+	// constants.
+	Phase string `json:"phase,omitempty"`
+	// AzureStatus is Azure's raw provisioning state as of LastTransitionTime,
+	// e.g. "Updating" or "Succeeded".
+	AzureStatus string `json:"azureStatus,omitempty"`
+	// Message is a human-readable summary of the current phase, mainly
+	// useful for Failed operations.
+	Message string `json:"message,omitempty"`
+
+	// StartTime is when this Operation was created.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// LastTransitionTime is when Phase or AzureStatus was last updated.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// CompletionTime is set once Phase reaches a terminal value.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// DurationSeconds is CompletionTime minus StartTime, once set.
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+
+	// Conditions holds the Operation's Ready or Failed condition once it
+	// completes, PreflightSucceeded, and, via abort policies, Aborted. Each
+	// type appears at most once and is updated in place, so earlier
+	// transitions are not kept. No InProgress condition is recorded; Phase
+	// carries that state.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Operation is the record of one Azure operation - a cluster upgrade, scale,
+// or similar long-running change - against an AKS cluster or one of its
+// agent pools.
+type Operation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OperationSpec   `json:"spec,omitempty"`
+	Status OperationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperationList is a list of Operations.
+type OperationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Operation `json:"items"`
+}
@@ -5,33 +5,60 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"kops/config"
 )
 
-// GetKubeClientForAKSCluster fetches the admin kubeconfig and returns a Kubernetes clientset
-func GetKubeClientForAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*kubernetes.Clientset, error) {
-	// Authenticate using Azure identity chain
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+// GetKubeClientForAKSCluster fetches the admin kubeconfig and returns a
+// Kubernetes clientset. The Azure credential used to authenticate is also
+// returned so callers can reuse it for later checks (e.g. a healthz probe
+// that confirms the credential is still obtaining tokens) instead of
+// creating a second one.
+func GetKubeClientForAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*kubernetes.Clientset, azcore.TokenCredential, error) {
+	_, kubeClient, cred, err := GetKubeConfigAndClientForAKSCluster(ctx, subscriptionID, resourceGroup, clusterName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeClient, cred, nil
+}
+
+// GetKubeConfigAndClientForAKSCluster is GetKubeClientForAKSCluster, plus
+// the rest.Config derived from the admin kubeconfig. Callers that need to
+// build other typed clientsets against the same cluster (e.g. the
+// versioned metrics API, which wants its own *metrics.Clientset) use this
+// instead of re-deriving a rest.Config from scratch.
+func GetKubeConfigAndClientForAKSCluster(ctx context.Context, subscriptionID, resourceGroup, clusterName string) (*rest.Config, *kubernetes.Clientset, azcore.TokenCredential, error) {
+	// Authenticate using whichever credential chain AZURE_CREDENTIAL_MODE
+	// (and its associated AZURE_TENANT_ID/AZURE_CLIENT_ID/... env vars)
+	// selects - see config.AzureConfig.NewCredential.
+	azureCfg := config.CredentialFieldsFromEnv()
+	azureCfg.SubscriptionID = subscriptionID
+	azureCfg.ResourceGroupName = resourceGroup
+	azureCfg.ClusterName = clusterName
+
+	cred, err := azureCfg.NewCredential(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to obtain Azure credential: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to obtain Azure credential: %w", err)
 	}
 
 	// Create AKS client
 	client, err := armcontainerservice.NewManagedClustersClient(subscriptionID, cred, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AKS client: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to create AKS client: %w", err)
 	}
 
 	// Fetch kubeconfig
 	res, err := client.ListClusterAdminCredentials(ctx, resourceGroup, clusterName, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get admin kubeconfig: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to get admin kubeconfig: %w", err)
 	}
 	if len(res.Kubeconfigs) == 0 {
-		return nil, fmt.Errorf("no kubeconfigs returned for cluster %s", clusterName)
+		return nil, nil, nil, fmt.Errorf("no kubeconfigs returned for cluster %s", clusterName)
 	}
 
 	kubeconfig := res.Kubeconfigs[0].Value
@@ -39,7 +66,7 @@ func GetKubeClientForAKSCluster(ctx context.Context, subscriptionID, resourceGro
 	// Convert to rest.Config
 	restCfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
 	}
 
 	restCfg.Timeout = 30 * time.Second
@@ -47,8 +74,8 @@ func GetKubeClientForAKSCluster(ctx context.Context, subscriptionID, resourceGro
 	// Create Kubernetes clientset
 	kubeClient, err := kubernetes.NewForConfig(restCfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Kubernetes clientset: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to create Kubernetes clientset: %w", err)
 	}
 
-	return kubeClient, nil
+	return restCfg, kubeClient, cred, nil
 }
@@ -1,22 +1,84 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"k8s.io/klog/v2"
+)
+
+// CredentialMode selects which Azure credential AzureConfig.NewCredential
+// constructs. The zero value, CredentialModeAuto, chains every supported
+// method and uses whichever succeeds first; the rest pin a single method
+// for environments (dev, CI) where auto-detection would pick the wrong one.
+type CredentialMode string
+
+const (
+	CredentialModeAuto             CredentialMode = "auto"
+	CredentialModeWorkloadIdentity CredentialMode = "workload-identity"
+	CredentialModeManagedIdentity  CredentialMode = "managed-identity"
+	CredentialModeCLI              CredentialMode = "cli"
+	CredentialModeClientSecret     CredentialMode = "client-secret"
+	CredentialModeDefault          CredentialMode = "default"
 )
 
 type AzureConfig struct {
 	SubscriptionID    string
 	ResourceGroupName string
 	ClusterName       string
+	CredentialMode    CredentialMode
+
+	// TenantID/ClientID identify the app registration or user-assigned
+	// managed identity NewCredential authenticates as. Both are optional
+	// for CredentialModeManagedIdentity (a system-assigned identity needs
+	// neither); TenantID and ClientID are required for
+	// CredentialModeClientSecret.
+	TenantID string
+	ClientID string
+
+	// ClientSecret and ClientCertificatePath authenticate
+	// CredentialModeClientSecret; set exactly one. ClientCertificatePassword
+	// decrypts ClientCertificatePath if the certificate is
+	// password-protected.
+	ClientSecret              string
+	ClientCertificatePath     string
+	ClientCertificatePassword string
+
+	// FederatedTokenFile overrides the workload identity webhook's default
+	// token path for CredentialModeWorkloadIdentity. Leave empty to use
+	// azidentity's own default (the projected volume AKS mounts
+	// automatically).
+	FederatedTokenFile string
 }
 
-func LoadAzureConfig() (AzureConfig, error) {
-	cfg := AzureConfig{
-		SubscriptionID:    os.Getenv("AZURE_SUBSCRIPTION_ID"),
-		ResourceGroupName: os.Getenv("AZURE_RESOURCE_GROUP"),
-		ClusterName:       os.Getenv("AZURE_CLUSTER_NAME"),
+// CredentialFieldsFromEnv populates the credential-selection fields of an
+// AzureConfig from the environment. It's split out from LoadAzureConfig so
+// a caller building one AzureConfig per cluster (e.g. ClusterCache, which
+// already has SubscriptionID/ResourceGroupName/ClusterName from elsewhere)
+// can pick up the same credential settings without going through
+// LoadAzureConfig's single-cluster required-env validation.
+func CredentialFieldsFromEnv() AzureConfig {
+	return AzureConfig{
+		CredentialMode:            CredentialMode(os.Getenv("AZURE_CREDENTIAL_MODE")),
+		TenantID:                  os.Getenv("AZURE_TENANT_ID"),
+		ClientID:                  os.Getenv("AZURE_CLIENT_ID"),
+		ClientSecret:              os.Getenv("AZURE_CLIENT_SECRET"),
+		ClientCertificatePath:     os.Getenv("AZURE_CLIENT_CERTIFICATE_PATH"),
+		ClientCertificatePassword: os.Getenv("AZURE_CLIENT_CERTIFICATE_PASSWORD"),
+		FederatedTokenFile:        os.Getenv("AZURE_FEDERATED_TOKEN_FILE"),
 	}
+}
+
+func LoadAzureConfig() (AzureConfig, error) {
+	cfg := CredentialFieldsFromEnv()
+	cfg.SubscriptionID = os.Getenv("AZURE_SUBSCRIPTION_ID")
+	cfg.ResourceGroupName = os.Getenv("AZURE_RESOURCE_GROUP")
+	cfg.ClusterName = os.Getenv("AZURE_CLUSTER_NAME")
 
 	if cfg.SubscriptionID == "" {
 		return cfg, fmt.Errorf("AZURE_SUBSCRIPTION_ID is required")
@@ -30,3 +92,159 @@ func LoadAzureConfig() (AzureConfig, error) {
 
 	return cfg, nil
 }
+
+// NewCredential builds the azcore.TokenCredential c.CredentialMode selects.
+// The zero value, CredentialModeAuto, chains every supported method, in
+// order: environment variables, workload identity (the standard for AKS
+// pods with a federated identity), managed identity, the Azure CLI, a
+// client secret/certificate, and finally DefaultAzureCredential as a last
+// resort (see newChainedCredential), so the same binary
+// authenticates in-cluster, from CI, or on a developer's laptop without a
+// code change - only AZURE_CREDENTIAL_MODE (or the equivalent AzureConfig
+// field) needs to change.
+func (c AzureConfig) NewCredential(ctx context.Context) (azcore.TokenCredential, error) {
+	switch c.CredentialMode {
+	case CredentialModeWorkloadIdentity:
+		return c.newWorkloadIdentityCredential()
+	case CredentialModeManagedIdentity:
+		return c.newManagedIdentityCredential()
+	case CredentialModeCLI:
+		return azidentity.NewAzureCLICredential(nil)
+	case CredentialModeClientSecret:
+		return c.newClientSecretCredential()
+	case CredentialModeDefault:
+		return azidentity.NewDefaultAzureCredential(nil)
+	case "", CredentialModeAuto:
+		return c.newChainedCredential()
+	default:
+		return nil, fmt.Errorf("unknown CredentialMode %q", c.CredentialMode)
+	}
+}
+
+func (c AzureConfig) newWorkloadIdentityCredential() (azcore.TokenCredential, error) {
+	opts := &azidentity.WorkloadIdentityCredentialOptions{}
+	if c.TenantID != "" {
+		opts.TenantID = c.TenantID
+	}
+	if c.ClientID != "" {
+		opts.ClientID = c.ClientID
+	}
+	if c.FederatedTokenFile != "" {
+		opts.TokenFilePath = c.FederatedTokenFile
+	}
+
+	cred, err := azidentity.NewWorkloadIdentityCredential(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workload identity credential: %w", err)
+	}
+	return cred, nil
+}
+
+func (c AzureConfig) newManagedIdentityCredential() (azcore.TokenCredential, error) {
+	opts := &azidentity.ManagedIdentityCredentialOptions{}
+	if c.ClientID != "" {
+		opts.ID = azidentity.ClientID(c.ClientID)
+	}
+
+	cred, err := azidentity.NewManagedIdentityCredential(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create managed identity credential: %w", err)
+	}
+	return cred, nil
+}
+
+// newClientSecretCredential builds a ClientSecretCredential or, if
+// ClientCertificatePath is set, a ClientCertificateCredential.
+func (c AzureConfig) newClientSecretCredential() (azcore.TokenCredential, error) {
+	if c.ClientCertificatePath != "" {
+		certData, err := os.ReadFile(c.ClientCertificatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate %s: %w", c.ClientCertificatePath, err)
+		}
+
+		certs, key, err := azidentity.ParseCertificates(certData, []byte(c.ClientCertificatePassword))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate %s: %w", c.ClientCertificatePath, err)
+		}
+
+		cred, err := azidentity.NewClientCertificateCredential(c.TenantID, c.ClientID, certs, key, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client certificate credential: %w", err)
+		}
+		return cred, nil
+	}
+
+	if c.ClientSecret == "" {
+		return nil, fmt.Errorf("ClientSecret or ClientCertificatePath must be set for CredentialModeClientSecret")
+	}
+
+	cred, err := azidentity.NewClientSecretCredential(c.TenantID, c.ClientID, c.ClientSecret, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client secret credential: %w", err)
+	}
+	return cred, nil
+}
+
+// newChainedCredential tries environment variables, workload identity,
+// managed identity, the Azure CLI, and a client secret/certificate, in that
+// order, falling back to DefaultAzureCredential. Each step is included only
+// if its preconditions look satisfied at construction time; azidentity's
+// ChainedTokenCredential falls through to the next credential at token
+// request time if an earlier one fails. Every entry is wrapped so the name
+// of whichever credential actually produces a token gets logged once,
+// since otherwise there's no way to tell which link in the chain a
+// deployment is actually relying on.
+func (c AzureConfig) newChainedCredential() (azcore.TokenCredential, error) {
+	var creds []azcore.TokenCredential
+
+	if env, err := azidentity.NewEnvironmentCredential(nil); err == nil {
+		creds = append(creds, &namedCredential{name: "environment", TokenCredential: env})
+	}
+	if c.FederatedTokenFile != "" || os.Getenv("AZURE_FEDERATED_TOKEN_FILE") != "" {
+		if wi, err := c.newWorkloadIdentityCredential(); err == nil {
+			creds = append(creds, &namedCredential{name: "workload-identity", TokenCredential: wi})
+		}
+	}
+	if mi, err := c.newManagedIdentityCredential(); err == nil {
+		creds = append(creds, &namedCredential{name: "managed-identity", TokenCredential: mi})
+	}
+	if cli, err := azidentity.NewAzureCLICredential(nil); err == nil {
+		creds = append(creds, &namedCredential{name: "cli", TokenCredential: cli})
+	}
+	if c.ClientSecret != "" || c.ClientCertificatePath != "" {
+		if cs, err := c.newClientSecretCredential(); err == nil {
+			creds = append(creds, &namedCredential{name: "client-secret", TokenCredential: cs})
+		}
+	}
+
+	def, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fallback default Azure credential: %w", err)
+	}
+	creds = append(creds, &namedCredential{name: "default", TokenCredential: def})
+
+	chain, err := azidentity.NewChainedTokenCredential(creds, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build chained Azure credential: %w", err)
+	}
+	return chain, nil
+}
+
+// namedCredential logs, once, the first time it successfully hands back a
+// token - so an operator can tell which link of newChainedCredential's
+// chain a running deployment actually authenticated through.
+type namedCredential struct {
+	name string
+	azcore.TokenCredential
+	logOnce sync.Once
+}
+
+func (n *namedCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	tok, err := n.TokenCredential.GetToken(ctx, opts)
+	if err == nil {
+		n.logOnce.Do(func() {
+			klog.InfoS("Azure credential chain authenticated", "credential", n.name)
+		})
+	}
+	return tok, err
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import "time"
+
+// abortExecutorWorkers is how many goroutines drain
+// ControllerContext.abortQueue. Azure's abort APIs are the slow,
+// rate-limited part of this subsystem, so this stays small to avoid piling
+// up concurrent abort calls against the same cluster.
+const abortExecutorWorkers = 2
+
+// startAbortExecutor is the abort-executor controller's
+// StartControllerFunc: it drains ControllerContext's abortQueue and makes
+// the actual Azure abort call for each request, off operation-monitor's
+// workers so a slow or failing Azure call can't stall rule evaluation for
+// every other Operation.
+func startAbortExecutor(ctx *ControllerContext) error {
+	for i := 0; i < abortExecutorWorkers; i++ {
+		go runAbortExecutorWorker(ctx)
+	}
+	return nil
+}
+
+func runAbortExecutorWorker(ctx *ControllerContext) {
+	for processNextAbortRequest(ctx) {
+	}
+}
+
+func processNextAbortRequest(ctx *ControllerContext) bool {
+	item, shutdown := ctx.abortQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer ctx.abortQueue.Done(item)
+
+	req := item.(abortRequest)
+
+	method := "AbortClusterOperation"
+	start := time.Now()
+	var err error
+	if req.pool != "" {
+		method = "AbortAgentPoolOperation"
+		_, err = abortAgentPool(ctx.AzureClient, req.opName, req.pool, req.reason)
+	} else {
+		_, err = abort(ctx.AzureClient, req.opName, req.reason)
+	}
+	ctx.Metrics.ObserveAbortLatency(time.Since(start))
+
+	result, code := "success", "success"
+	if err != nil {
+		result, code = "failure", "error"
+	}
+	ctx.Metrics.ObserveAbortAttempt(result)
+	ctx.Metrics.ObserveAzureAPICall(method, code)
+
+	emitAbortEvent(ctx.Recorder, req.opName, err)
+	updateAbortStatus(ctx.DynClient, req.opName, err == nil)
+
+	ctx.abortQueue.Forget(item)
+	return true
+}
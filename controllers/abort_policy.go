@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "kops/api/v1"
+)
+
+// +kubebuilder:rbac:groups=core.kops.aks.microsoft.com,resources=abortpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.kops.aks.microsoft.com,resources=operations,verbs=get;list;watch;update
+
+const (
+	// AbortRateLimitWindow is the minimum time between abort attempts
+	// against the same Operation CR, so a stuck 409 loop from Azure
+	// doesn't turn into a hot loop of abort calls.
+	AbortRateLimitWindow = 2 * time.Minute
+
+	ConditionTypeAborted = "Aborted"
+
+	ReasonAbortedByPolicy  = "AbortedByPolicy"
+	ReasonAbortSkipped409  = "AbortSkipped409"
+	ReasonAbortSkippedDry  = "AbortSkippedDryRun"
+	ReasonAbortRateLimited = "AbortRateLimited"
+)
+
+// abortPolicySpec is the parsed, validated form of an AbortPolicy CR's
+// spec: what in-progress Operations it targets, and what to do about
+// them once they've run for too long.
+type abortPolicySpec struct {
+	OperationTypeSelector []string
+	MaxDurationSeconds    int64
+	ClusterSelector       map[string]string
+	DryRun                bool
+}
+
+type abortPolicy struct {
+	Name string
+	Spec abortPolicySpec
+}
+
+// enforceAbortPolicies lists every AbortPolicy CR in the namespace and,
+// for each in-progress Operation belonging to this cluster, aborts the
+// underlying Azure operation when a policy's operationTypeSelector,
+// maxDurationSeconds, and clusterSelector all match. This is what turns
+// AbortClusterOperation/AbortAgentPoolOperation from unused plumbing into
+// a real safety mechanism (e.g. auto-cancel a cluster stuck Upgrading for
+// more than two hours).
+func (r *OperationReconciler) enforceAbortPolicies(ctx context.Context) error {
+	policies, err := r.listAbortPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list abort policies: %w", err)
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	operations := &apiv1.OperationList{}
+
+	listOpts := []client.ListOption{
+		client.InNamespace(r.Namespace),
+		client.MatchingLabels{"azure.cluster.name": r.ClusterName},
+	}
+	if err := r.Client.List(ctx, operations, listOpts...); err != nil {
+		return fmt.Errorf("failed to list operations: %w", err)
+	}
+
+	for i := range operations.Items {
+		op := &operations.Items[i]
+
+		if op.Status.Phase != apiv1.PhaseInProgress {
+			continue
+		}
+
+		policy := matchingAbortPolicy(policies, op)
+		if policy == nil {
+			continue
+		}
+
+		if err := r.applyAbortPolicy(ctx, op, policy); err != nil {
+			klog.ErrorS(err, "Failed to apply abort policy", "operation", op.GetName(), "policy", policy.Name)
+		}
+	}
+
+	return nil
+}
+
+func (r *OperationReconciler) listAbortPolicies(ctx context.Context) ([]abortPolicy, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "core.kops.aks.microsoft.com",
+		Version: "v1",
+		Kind:    "AbortPolicyList",
+	})
+
+	if err := r.Client.List(ctx, list, client.InNamespace(r.Namespace)); err != nil {
+		return nil, err
+	}
+
+	policies := make([]abortPolicy, 0, len(list.Items))
+	for i := range list.Items {
+		spec, err := parseAbortPolicySpec(&list.Items[i])
+		if err != nil {
+			klog.ErrorS(err, "Skipping invalid AbortPolicy", "name", list.Items[i].GetName())
+			continue
+		}
+		policies = append(policies, abortPolicy{Name: list.Items[i].GetName(), Spec: spec})
+	}
+
+	return policies, nil
+}
+
+// parseAbortPolicySpec validates and extracts an AbortPolicy's spec. There's
+// no admission webhook in front of this CRD, so this doubles as the
+// validation layer: a malformed policy (missing clusterSelector keys
+// aside, which are optional) is rejected and skipped rather than applied
+// with too-permissive zero-value semantics.
+func parseAbortPolicySpec(cr *unstructured.Unstructured) (abortPolicySpec, error) {
+	var spec abortPolicySpec
+
+	maxDuration, found, err := unstructured.NestedInt64(cr.Object, "spec", "maxDurationSeconds")
+	if err != nil {
+		return spec, fmt.Errorf("spec.maxDurationSeconds: %w", err)
+	}
+	if !found || maxDuration <= 0 {
+		return spec, fmt.Errorf("spec.maxDurationSeconds must be a positive integer")
+	}
+	spec.MaxDurationSeconds = maxDuration
+
+	opTypes, _, err := unstructured.NestedStringSlice(cr.Object, "spec", "operationTypeSelector")
+	if err != nil {
+		return spec, fmt.Errorf("spec.operationTypeSelector: %w", err)
+	}
+	if len(opTypes) == 0 {
+		return spec, fmt.Errorf("spec.operationTypeSelector must not be empty")
+	}
+	spec.OperationTypeSelector = opTypes
+
+	selector, _, err := unstructured.NestedStringMap(cr.Object, "spec", "clusterSelector")
+	if err != nil {
+		return spec, fmt.Errorf("spec.clusterSelector: %w", err)
+	}
+	spec.ClusterSelector = selector
+
+	dryRun, _, err := unstructured.NestedBool(cr.Object, "spec", "dryRun")
+	if err != nil {
+		return spec, fmt.Errorf("spec.dryRun: %w", err)
+	}
+	spec.DryRun = dryRun
+
+	return spec, nil
+}
+
+// matchingAbortPolicy returns the first policy whose selectors match op
+// and whose maxDurationSeconds has elapsed since the operation started,
+// or nil if none applies yet.
+func matchingAbortPolicy(policies []abortPolicy, op *apiv1.Operation) *abortPolicy {
+	if op.Status.StartTime == nil {
+		return nil
+	}
+	age := time.Since(op.Status.StartTime.Time)
+
+	for i := range policies {
+		p := &policies[i]
+		if age.Seconds() < float64(p.Spec.MaxDurationSeconds) {
+			continue
+		}
+		if !containsFold(p.Spec.OperationTypeSelector, op.Spec.OperationType) {
+			continue
+		}
+		if !labelsMatch(op.GetLabels(), p.Spec.ClusterSelector) {
+			continue
+		}
+		return p
+	}
+
+	return nil
+}
+
+// applyAbortPolicy carries out (or, for dryRun, merely records) the abort
+// a matching policy calls for, rate-limited per Operation so repeated
+// 409s from Azure don't turn into a hammering loop.
+func (r *OperationReconciler) applyAbortPolicy(ctx context.Context, op *apiv1.Operation, policy *abortPolicy) error {
+	if lastAttempt, ok := op.GetAnnotations()["azure.abort.lastAttempt"]; ok {
+		if t, err := time.Parse(time.RFC3339, lastAttempt); err == nil && time.Since(t) < AbortRateLimitWindow {
+			klog.V(2).InfoS("Skipping abort, rate limited", "operation", op.GetName())
+			return nil
+		}
+	}
+
+	reason := fmt.Sprintf("policy %s: operation has been running longer than maxDurationSeconds=%d", policy.Name, policy.Spec.MaxDurationSeconds)
+
+	if policy.Spec.DryRun {
+		return r.recordAbortCondition(ctx, op, ReasonAbortSkippedDry, reason)
+	}
+
+	var abortErr error
+	if op.Spec.AgentPoolName != "" {
+		abortErr = r.Azure.AbortAgentPoolOperation(ctx, op.Spec.AgentPoolName, reason)
+	} else {
+		abortErr = r.Azure.AbortClusterOperation(ctx, reason)
+	}
+
+	annotations := op.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations["azure.abort.lastAttempt"] = time.Now().Format(time.RFC3339)
+	op.SetAnnotations(annotations)
+	if err := r.Client.Update(ctx, op); err != nil {
+		return fmt.Errorf("failed to stamp abort attempt on operation %s: %w", op.GetName(), err)
+	}
+
+	if abortErr != nil {
+		if strings.Contains(abortErr.Error(), "409") || strings.Contains(abortErr.Error(), "Conflict") {
+			return r.recordAbortCondition(ctx, op, ReasonAbortSkipped409, abortErr.Error())
+		}
+		return fmt.Errorf("abort failed: %w", abortErr)
+	}
+
+	return r.recordAbortCondition(ctx, op, ReasonAbortedByPolicy, reason)
+}
+
+// recordAbortCondition appends an Aborted condition to the Operation's
+// status via the status subresource, giving operators an audit trail of
+// what kops decided to abort and why. This is a separate call from
+// applyAbortPolicy's metadata update above, so the annotation and the
+// status write can't be torn apart into a single non-atomic PUT.
+func (r *OperationReconciler) recordAbortCondition(ctx context.Context, op *apiv1.Operation, reason, message string) error {
+	meta.SetStatusCondition(&op.Status.Conditions, metav1.Condition{
+		Type:    ConditionTypeAborted,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+
+	if err := r.Client.Status().Update(ctx, op); err != nil {
+		return fmt.Errorf("failed to update operation %s with abort condition: %w", op.GetName(), err)
+	}
+
+	klog.InfoS("Recorded abort policy outcome", "operation", op.GetName(), "reason", reason)
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func labelsMatch(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"kops/internal/azure"
+)
+
+// StartControllerFunc wires up and starts one named controller against the
+// shared ControllerContext. It returns once the controller's informers have
+// synced and its workers are running in their own goroutines - it must not
+// block for the controller's lifetime.
+type StartControllerFunc func(ctx *ControllerContext) error
+
+// Manager is a registry of named controllers, analogous to the one
+// kube-controller-manager and kubeadmiral build their controller-manager
+// binaries around: controllers register themselves under a name, and the
+// --controllers flag decides which of the registered names actually start.
+type Manager struct {
+	knownControllers map[string]StartControllerFunc
+}
+
+// NewManager returns an empty Manager ready for Register calls.
+func NewManager() *Manager {
+	return &Manager{knownControllers: make(map[string]StartControllerFunc)}
+}
+
+// Register adds a controller under name. Registering the same name twice
+// overwrites the previous registration.
+func (m *Manager) Register(name string, start StartControllerFunc) {
+	m.knownControllers[name] = start
+}
+
+// ParseControllers turns a --controllers flag value such as "foo,-bar"
+// into the set of controller names that should be disabled. Every
+// registered controller runs by default; a leading "-" opts one out. A
+// name with no "-" prefix is accepted but has no effect, for symmetry with
+// kube-controller-manager's flag.
+func ParseControllers(flagValue string) sets.Set[string] {
+	disabled := sets.New[string]()
+	for _, name := range strings.Split(flagValue, ",") {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "-") {
+			disabled.Insert(strings.TrimPrefix(name, "-"))
+		}
+	}
+	return disabled
+}
+
+// Start runs every registered controller whose name isn't in disabled. It
+// returns the first error a controller's StartControllerFunc returns;
+// controllers that already started keep running in their own goroutines
+// regardless.
+func (m *Manager) Start(ctx *ControllerContext, disabled sets.Set[string]) error {
+	for name, start := range m.knownControllers {
+		if disabled.Has(name) {
+			fmt.Printf("Controller %s disabled by --controllers, skipping.\n", name)
+			continue
+		}
+		fmt.Printf("Starting controller %s\n", name)
+		if err := start(ctx); err != nil {
+			return fmt.Errorf("failed to start controller %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ControllerContext bundles the clients and cross-controller plumbing the
+// operation-monitor, metrics-watcher, and abort-executor controllers share,
+// so StartControllerFunc implementations take one argument instead of
+// threading four clients and a recorder through by hand.
+type ControllerContext struct {
+	AzureClient *azure.Client
+	TypedClient kubernetes.Interface
+	DynClient   dynamic.Interface
+	BaseClient  kubernetes.Interface
+	Recorder    record.EventRecorder
+	Metrics     *MetricsServer
+
+	// operations tracks which Operation CR names operation-monitor
+	// currently knows about, so metrics-watcher can re-enqueue all of them
+	// when the shared metrics ConfigMap changes without reaching into
+	// operation-monitor's internals.
+	operations *activeOperations
+
+	// operationQueue holds Operation CR names awaiting a rule evaluation.
+	// operation-monitor's informer handlers and metrics-watcher both
+	// enqueue to it; operation-monitor's workers drain it.
+	operationQueue workqueue.RateLimitingInterface
+
+	// abortQueue holds abortRequests produced by operation-monitor once a
+	// violation has sustained long enough to act on. abort-executor's
+	// workers drain it, keeping the actual Azure call - and its latency -
+	// off operation-monitor's workers.
+	abortQueue workqueue.RateLimitingInterface
+
+	readyMu           sync.Mutex
+	syncedControllers map[string]bool
+	metricsLastSeenAt time.Time
+}
+
+// NewControllerContext builds the shared state StartHealthMonitoring's
+// three controllers are registered against.
+func NewControllerContext(azureClient *azure.Client, typedClient kubernetes.Interface, dynClient dynamic.Interface, baseClient kubernetes.Interface) *ControllerContext {
+	return &ControllerContext{
+		AzureClient:       azureClient,
+		TypedClient:       typedClient,
+		DynClient:         dynClient,
+		BaseClient:        baseClient,
+		Recorder:          newEventRecorder(typedClient),
+		Metrics:           NewMetricsServer(),
+		operations:        newActiveOperations(),
+		operationQueue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		abortQueue:        workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		syncedControllers: make(map[string]bool),
+	}
+}
+
+// markControllerSynced records that name's informer cache has finished its
+// initial sync, so the informer-cache-sync readyz check built by
+// StartHealthMonitoring can report ready once every registered controller
+// has called this.
+func (c *ControllerContext) markControllerSynced(name string) {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	c.syncedControllers[name] = true
+}
+
+// controllerSynced reports whether name has called markControllerSynced.
+func (c *ControllerContext) controllerSynced(name string) bool {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	return c.syncedControllers[name]
+}
+
+// markMetricsSeen records that metrics-watcher just observed a fresh
+// metrics-store ConfigMap, for the metrics-store-freshness readyz check.
+func (c *ControllerContext) markMetricsSeen() {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	c.metricsLastSeenAt = time.Now()
+}
+
+// metricsAge reports how long it's been since markMetricsSeen last ran. ok
+// is false if metrics-watcher hasn't observed the ConfigMap yet at all.
+func (c *ControllerContext) metricsAge() (age time.Duration, ok bool) {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	if c.metricsLastSeenAt.IsZero() {
+		return 0, false
+	}
+	return time.Since(c.metricsLastSeenAt), true
+}
+
+// abortRequest is one unit of work for abort-executor: a sustained
+// violation operation-monitor decided should abort either the whole
+// operation (pool empty) or a single agent pool.
+type abortRequest struct {
+	opName string
+	pool   string
+	reason string
+}
+
+// activeOperations is the set of Operation CR names operation-monitor is
+// currently watching, shared with metrics-watcher via ControllerContext.
+type activeOperations struct {
+	mu    sync.Mutex
+	names map[string]struct{}
+}
+
+func newActiveOperations() *activeOperations {
+	return &activeOperations{names: make(map[string]struct{})}
+}
+
+func (a *activeOperations) add(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.names[name] = struct{}{}
+}
+
+func (a *activeOperations) remove(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.names, name)
+}
+
+func (a *activeOperations) list() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]string, 0, len(a.names))
+	for name := range a.names {
+		out = append(out, name)
+	}
+	return out
+}
+
+// emitAbortEvent records the outcome of one abort attempt against opName -
+// Normal/"AbortSucceeded" if the Azure call succeeded, Warning/"AbortFailed"
+// with the error otherwise.
+func emitAbortEvent(recorder record.EventRecorder, opName string, abortErr error) {
+	if abortErr != nil {
+		recorder.Eventf(operationRef(opName), corev1.EventTypeWarning, "AbortFailed", "abort request failed: %v", abortErr)
+		return
+	}
+	recorder.Event(operationRef(opName), corev1.EventTypeNormal, "AbortSucceeded", "abort request sent successfully")
+}
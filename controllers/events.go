@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// newEventRecorder builds an EventRecorder that posts to typedClient's
+// Events API, so every threshold violation and abort attempt shows up under
+// `kubectl describe operation` alongside the CR's own conditions, instead
+// of only in this pod's logs.
+func newEventRecorder(typedClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(klog.Infof)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: typedClient.CoreV1().Events("")})
+
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "kops-health-controller"})
+}
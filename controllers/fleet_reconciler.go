@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v4"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kops/config"
+	"kops/internal/azure"
+)
+
+const (
+	// FleetMemberTag is the Azure resource tag an AKS cluster must carry,
+	// set to "true", to be picked up as a fleet member.
+	FleetMemberTag = "kops.aks.microsoft.com/managed"
+
+	// FleetDiscoveryInterval controls how often fleet membership is
+	// refreshed against Azure.
+	FleetDiscoveryInterval = 5 * time.Minute
+)
+
+// FleetConfig configures a FleetReconciler.
+type FleetConfig struct {
+	SubscriptionID string
+	FleetName      string
+	Namespace      string
+}
+
+// fleetMember tracks the running worker for one AKS cluster discovered in
+// the fleet.
+type fleetMember struct {
+	reconciler *OperationReconciler
+	cancel     context.CancelFunc
+}
+
+// FleetReconciler watches every AKS cluster tagged for monitoring within a
+// subscription instead of the single (ResourceGroup, ClusterName) pair
+// OperationReconciler is bound to. It periodically re-lists clusters with
+// ManagedClustersClient.NewListPager, then starts or stops one
+// OperationReconciler goroutine per member as clusters join or leave the
+// fleet, so one kops deployment can watch operations across dozens of AKS
+// clusters that share a controller-runtime client.
+type FleetReconciler struct {
+	Client client.Client
+	Config FleetConfig
+
+	clusters *armcontainerservice.ManagedClustersClient
+
+	mu      sync.Mutex
+	members map[string]*fleetMember // keyed by "resourceGroup/clusterName"
+
+	stopCh chan struct{}
+}
+
+// NewFleetReconciler creates a FleetReconciler. cred is used to list
+// clusters at subscription scope; each member's azure.Client builds its own
+// credential from the environment (config.CredentialFieldsFromEnv) rather
+// than reusing cred.
+func NewFleetReconciler(crClient client.Client, cred azcore.TokenCredential, cfg FleetConfig) (*FleetReconciler, error) {
+	if crClient == nil {
+		return nil, fmt.Errorf("client cannot be nil")
+	}
+	if cred == nil {
+		return nil, fmt.Errorf("azure credential cannot be nil")
+	}
+	if cfg.SubscriptionID == "" {
+		return nil, fmt.Errorf("subscription id cannot be empty")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = "default"
+	}
+
+	clusters, err := armcontainerservice.NewManagedClustersClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create managed clusters client: %w", err)
+	}
+
+	return &FleetReconciler{
+		Client:   crClient,
+		Config:   cfg,
+		clusters: clusters,
+		members:  make(map[string]*fleetMember),
+		stopCh:   make(chan struct{}),
+	}, nil
+}
+
+// Start discovers the current fleet membership and begins the background
+// loop that keeps it in sync.
+func (f *FleetReconciler) Start(ctx context.Context) error {
+	klog.InfoS("Starting fleet monitoring", "subscription", f.Config.SubscriptionID, "fleet", f.Config.FleetName)
+
+	if err := f.syncMembers(ctx); err != nil {
+		return fmt.Errorf("initial fleet discovery failed: %w", err)
+	}
+
+	go f.discoveryLoop(ctx)
+	return nil
+}
+
+// Stop halts the discovery loop and every running member worker.
+func (f *FleetReconciler) Stop() {
+	close(f.stopCh)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, m := range f.members {
+		m.reconciler.Stop()
+		m.cancel()
+		delete(f.members, key)
+	}
+}
+
+func (f *FleetReconciler) discoveryLoop(ctx context.Context) {
+	ticker := time.NewTicker(FleetDiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.syncMembers(ctx); err != nil {
+				klog.ErrorS(err, "Fleet discovery failed")
+			}
+		case <-f.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// syncMembers lists every ManagedCluster in the subscription, starts a
+// worker for any newly tagged member, and stops workers for members that
+// are no longer present or no longer tagged.
+func (f *FleetReconciler) syncMembers(ctx context.Context) error {
+	seen := make(map[string]bool)
+
+	pager := f.clusters.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list clusters: %w", err)
+		}
+
+		for _, cluster := range page.Value {
+			if cluster == nil || cluster.Name == nil || cluster.ID == nil {
+				continue
+			}
+			if !isFleetMember(cluster.Tags) {
+				continue
+			}
+
+			resourceGroup := resourceGroupFromID(*cluster.ID)
+			if resourceGroup == "" {
+				klog.ErrorS(fmt.Errorf("malformed resource id"), "Skipping cluster", "id", *cluster.ID)
+				continue
+			}
+
+			key := memberKey(resourceGroup, *cluster.Name)
+			seen[key] = true
+
+			f.mu.Lock()
+			_, exists := f.members[key]
+			f.mu.Unlock()
+			if exists {
+				continue
+			}
+
+			if err := f.startMember(ctx, resourceGroup, *cluster.Name); err != nil {
+				klog.ErrorS(err, "Failed to start fleet member", "resourceGroup", resourceGroup, "cluster", *cluster.Name)
+			}
+		}
+	}
+
+	f.mu.Lock()
+	for key, m := range f.members {
+		if seen[key] {
+			continue
+		}
+		klog.InfoS("Fleet member no longer present, stopping worker", "member", key)
+		m.reconciler.Stop()
+		m.cancel()
+		delete(f.members, key)
+	}
+	f.mu.Unlock()
+
+	return nil
+}
+
+func (f *FleetReconciler) startMember(ctx context.Context, resourceGroup, clusterName string) error {
+	azureCfg := config.CredentialFieldsFromEnv()
+	azureCfg.SubscriptionID = f.Config.SubscriptionID
+	azureCfg.ResourceGroupName = resourceGroup
+	azureCfg.ClusterName = clusterName
+
+	azureClient, err := azure.NewClient(ctx, azureCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create azure client: %w", err)
+	}
+
+	reconciler, err := NewOperationReconciler(f.Client, azureClient, Config{
+		Namespace:     f.Config.Namespace,
+		ResourceGroup: resourceGroup,
+		ClusterName:   clusterName,
+		FleetLabels: map[string]string{
+			"azure.subscription.id": f.Config.SubscriptionID,
+			"azure.fleet.name":      f.Config.FleetName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create operation reconciler: %w", err)
+	}
+
+	memberCtx, cancel := context.WithCancel(ctx)
+	if err := reconciler.Start(memberCtx); err != nil {
+		cancel()
+		return fmt.Errorf("failed to start operation reconciler: %w", err)
+	}
+
+	f.mu.Lock()
+	f.members[memberKey(resourceGroup, clusterName)] = &fleetMember{reconciler: reconciler, cancel: cancel}
+	f.mu.Unlock()
+
+	klog.InfoS("Started fleet member worker", "resourceGroup", resourceGroup, "cluster", clusterName)
+	return nil
+}
+
+// GetStatus reports the fleet-wide reconciler's view of the world.
+func (f *FleetReconciler) GetStatus() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	members := make([]string, 0, len(f.members))
+	for key := range f.members {
+		members = append(members, key)
+	}
+
+	return map[string]interface{}{
+		"subscription":      f.Config.SubscriptionID,
+		"fleet":             f.Config.FleetName,
+		"namespace":         f.Config.Namespace,
+		"members":           members,
+		"discoveryInterval": FleetDiscoveryInterval.String(),
+	}
+}
+
+func memberKey(resourceGroup, clusterName string) string {
+	return resourceGroup + "/" + clusterName
+}
+
+func isFleetMember(tags map[string]*string) bool {
+	if tags == nil {
+		return false
+	}
+	value, ok := tags[FleetMemberTag]
+	return ok && value != nil && strings.EqualFold(*value, "true")
+}
+
+// resourceGroupFromID extracts the resource group segment from an Azure
+// resource ID of the form
+// /subscriptions/<sub>/resourceGroups/<rg>/providers/....
+func resourceGroupFromID(id string) string {
+	parts := strings.Split(id, "/")
+	for i, part := range parts {
+		if strings.EqualFold(part, "resourceGroups") && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog/v2"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+
+	"kops/internal/clustercache"
+	"kops/metric_collector"
+)
+
+// DefaultMetricsCollectionInterval is how often MetricsCollectorRunnable
+// collects metrics from every registered cluster, matching the cadence the
+// old ticker-driven loop in main.go used.
+const DefaultMetricsCollectionInterval = 30 * time.Second
+
+// MetricsCollectorRunnable periodically collects metrics from every cluster
+// in Cache and stores them via StorageBackend/Exporter, replacing the bare
+// time.Ticker loop main.go used to run directly. It implements
+// manager.Runnable so a ctrl.Manager starts and stops it like any other
+// component, and manager.LeaderElectionRunnable so only the elected leader
+// ever collects - running the same collection pass on every replica would
+// mean every pod hitting Azure and the storage backend on the same cadence.
+type MetricsCollectorRunnable struct {
+	Cache          *clustercache.Cache
+	ClusterKeys    []clustercache.ClusterKey
+	StorageBackend metric_collector.StorageBackend
+	Exporter       metric_collector.MetricsSink
+	// Sink records collection bookkeeping (count, last-updated). Optional:
+	// if nil, bookkeeping is skipped.
+	Sink MetricsSink
+	// Interval between collection passes. Defaults to
+	// DefaultMetricsCollectionInterval if zero.
+	Interval time.Duration
+}
+
+// Start runs one collection pass immediately, then one every Interval,
+// until ctx is cancelled. It satisfies manager.Runnable.
+func (m *MetricsCollectorRunnable) Start(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultMetricsCollectionInterval
+	}
+
+	count := 1
+	m.collectAll(ctx)
+	m.recordCollection(ctx, count)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			count++
+			m.collectAll(ctx)
+			m.recordCollection(ctx, count)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection reports true so a ctrl.Manager only runs this
+// Runnable on the elected leader. It satisfies
+// manager.LeaderElectionRunnable.
+func (m *MetricsCollectorRunnable) NeedLeaderElection() bool {
+	return true
+}
+
+// collectAll runs one metrics collection pass against every cluster in
+// ClusterKeys, using Cache to obtain (and, if a cluster dropped out,
+// reconnect) each one's Kubernetes and metrics clients. A failure against
+// one cluster is logged and skipped rather than aborting the rest of the
+// pass.
+func (m *MetricsCollectorRunnable) collectAll(ctx context.Context) {
+	for _, key := range m.ClusterKeys {
+		kubeClient, err := m.Cache.GetClient(ctx, key)
+		if err != nil {
+			klog.ErrorS(err, "Error getting Kubernetes client", "cluster", key)
+			continue
+		}
+
+		restConfig, err := m.Cache.GetRestConfig(ctx, key)
+		if err != nil {
+			klog.ErrorS(err, "Error getting rest config", "cluster", key)
+			continue
+		}
+
+		metricsClient, err := metrics.NewForConfig(restConfig)
+		if err != nil {
+			klog.ErrorS(err, "Error creating metrics client", "cluster", key)
+			continue
+		}
+
+		if err := metric_collector.CollectAndStoreMetrics(kubeClient, metricsClient, m.StorageBackend, m.Exporter); err != nil {
+			klog.ErrorS(err, "Error collecting metrics", "cluster", key)
+			continue
+		}
+
+		klog.V(1).InfoS("Collection completed successfully", "cluster", key)
+	}
+}
+
+func (m *MetricsCollectorRunnable) recordCollection(ctx context.Context, count int) {
+	if m.Sink == nil {
+		return
+	}
+	if err := m.Sink.RecordCollection(ctx, count, time.Now()); err != nil {
+		klog.ErrorS(err, "Failed to record metrics collection bookkeeping")
+	}
+}
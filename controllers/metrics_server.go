@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"kops/metric_collector/healthz"
+)
+
+// DefaultMetricsServerAddr is used when StartHealthMonitoring's caller
+// doesn't override it.
+const DefaultMetricsServerAddr = ":9091"
+
+// MetricsServer gives operation-monitor, metrics-watcher, and
+// abort-executor their own observability, separate from the cluster
+// metrics exporter package collects: how many Operations are being
+// watched, how often rules fire, how abort attempts against Azure are
+// going, and whether this subsystem's own informers are caught up.
+type MetricsServer struct {
+	registry *prometheus.Registry
+
+	operationMonitorsActive  prometheus.Gauge
+	thresholdViolationsTotal *prometheus.CounterVec
+	abortAttemptsTotal       *prometheus.CounterVec
+	abortLatencySeconds      prometheus.Histogram
+	azureAPICallsTotal       *prometheus.CounterVec
+}
+
+// NewMetricsServer creates a MetricsServer with every collector registered.
+func NewMetricsServer() *MetricsServer {
+	registry := prometheus.NewRegistry()
+
+	ms := &MetricsServer{
+		registry: registry,
+		operationMonitorsActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_operation_monitors_active", Help: "Number of Operation CRs operation-monitor currently holds hysteresis state for.",
+		}),
+		thresholdViolationsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kops_threshold_violations_total", Help: "Rule matches against the metrics snapshot, by rule name and severity.",
+		}, []string{"rule", "severity"}),
+		abortAttemptsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kops_abort_attempts_total", Help: "Abort requests abort-executor has sent to Azure, by result.",
+		}, []string{"result"}),
+		abortLatencySeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "kops_abort_latency_seconds", Help: "Time abort-executor spent waiting on an Azure abort call.", Buckets: prometheus.DefBuckets,
+		}),
+		azureAPICallsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kops_azure_api_calls_total", Help: "Azure API calls this subsystem made, by method and outcome.",
+		}, []string{"method", "code"}),
+	}
+
+	registry.MustRegister(
+		ms.operationMonitorsActive,
+		ms.thresholdViolationsTotal,
+		ms.abortAttemptsTotal,
+		ms.abortLatencySeconds,
+		ms.azureAPICallsTotal,
+	)
+
+	return ms
+}
+
+// SetActiveOperations records how many Operation CRs operation-monitor is
+// currently watching.
+func (ms *MetricsServer) SetActiveOperations(n int) {
+	ms.operationMonitorsActive.Set(float64(n))
+}
+
+// ObserveViolation records one rule match.
+func (ms *MetricsServer) ObserveViolation(rule, severity string) {
+	ms.thresholdViolationsTotal.WithLabelValues(rule, severity).Inc()
+}
+
+// ObserveAbortAttempt records one abort-executor attempt; result is
+// "success" or "failure".
+func (ms *MetricsServer) ObserveAbortAttempt(result string) {
+	ms.abortAttemptsTotal.WithLabelValues(result).Inc()
+}
+
+// ObserveAbortLatency records how long an Azure abort call took.
+func (ms *MetricsServer) ObserveAbortLatency(d time.Duration) {
+	ms.abortLatencySeconds.Observe(d.Seconds())
+}
+
+// ObserveAzureAPICall records one call this subsystem made against the
+// Azure API; code is "success" or "error".
+func (ms *MetricsServer) ObserveAzureAPICall(method, code string) {
+	ms.azureAPICallsTotal.WithLabelValues(method, code).Inc()
+}
+
+// Start mounts /metrics, /healthz, and /readyz on addr and serves them. It
+// blocks, so callers should run it in a goroutine; if addr is empty,
+// DefaultMetricsServerAddr is used. checks gate /readyz only - /healthz
+// always uses healthz.PingHealthz, since liveness for this subsystem just
+// means the HTTP server is still answering.
+func (ms *MetricsServer) Start(addr string, checks ...healthz.HealthChecker) error {
+	if addr == "" {
+		addr = DefaultMetricsServerAddr
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(ms.registry, promhttp.HandlerOpts{}))
+	healthz.InstallHandler(mux, "/healthz", healthz.PingHealthz)
+	healthz.InstallHandler(mux, "/readyz", checks...)
+
+	log.Printf("Health-monitoring metrics endpoint running on %s\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		return fmt.Errorf("failed to start health-monitoring metrics server: %w", err)
+	}
+	return nil
+}
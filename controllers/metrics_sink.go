@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MetricsSink records bookkeeping about completed metrics collection passes
+// - how many have run and when the most recent one finished - separately
+// from StorageBackend, which holds the metrics data itself. It's an
+// interface so ConfigMapMetricsSink's single-object storage, capped at
+// etcd's ~1MiB object size, can be swapped for a CRD-backed sink later
+// without MetricsCollectorRunnable changing at all.
+type MetricsSink interface {
+	RecordCollection(ctx context.Context, count int, at time.Time) error
+}
+
+// ConfigMapMetricsSink is today's MetricsSink: it stores collection
+// bookkeeping as two keys on a single ConfigMap, created on first use.
+type ConfigMapMetricsSink struct {
+	Client    client.Client
+	Namespace string
+	Name      string
+}
+
+// NewConfigMapMetricsSink returns a ConfigMapMetricsSink writing to
+// name/namespace via c.
+func NewConfigMapMetricsSink(c client.Client, namespace, name string) *ConfigMapMetricsSink {
+	return &ConfigMapMetricsSink{Client: c, Namespace: namespace, Name: name}
+}
+
+// RecordCollection creates the ConfigMap on the first call and updates its
+// total_collections/last_updated keys on every subsequent one.
+func (s *ConfigMapMetricsSink) RecordCollection(ctx context.Context, count int, at time.Time) error {
+	var cm corev1.ConfigMap
+	err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.Name}, &cm)
+	if apierrors.IsNotFound(err) {
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+			Data:       collectionData(count, at),
+		}
+		return s.Client.Create(ctx, &cm)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get configmap %s/%s: %w", s.Namespace, s.Name, err)
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	for k, v := range collectionData(count, at) {
+		cm.Data[k] = v
+	}
+	return s.Client.Update(ctx, &cm)
+}
+
+func collectionData(count int, at time.Time) map[string]string {
+	return map[string]string{
+		"total_collections": strconv.Itoa(count),
+		"last_updated":      at.Format(time.RFC3339),
+	}
+}
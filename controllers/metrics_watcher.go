@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// startMetricsWatcher is the metrics-watcher controller's
+// StartControllerFunc: it watches the metrics-store ConfigMap and, on
+// every update, re-enqueues every Operation operation-monitor currently
+// knows about onto ControllerContext's operationQueue.
+func startMetricsWatcher(ctx *ControllerContext) error {
+	typedFactory := informers.NewSharedInformerFactoryWithOptions(ctx.TypedClient, time.Minute*10, informers.WithNamespace("default"))
+	cmInformer := typedFactory.Core().V1().ConfigMaps().Informer()
+	cmInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if cm := obj.(*corev1.ConfigMap); cm.Name == "metrics-store" {
+				ctx.markMetricsSeen()
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			cm := newObj.(*corev1.ConfigMap)
+			if cm.Name != "metrics-store" {
+				return
+			}
+			ctx.markMetricsSeen()
+			for _, name := range ctx.operations.list() {
+				ctx.operationQueue.Add(name)
+			}
+		},
+	})
+
+	stop := make(chan struct{})
+	go typedFactory.Start(stop)
+	if !cache.WaitForCacheSync(stop, cmInformer.HasSynced) {
+		return fmt.Errorf("metrics-watcher: failed to sync ConfigMap informer cache")
+	}
+	ctx.markControllerSynced("metrics-watcher")
+	return nil
+}
@@ -2,41 +2,125 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	stderrors "errors"
 	"fmt"
+	"math/rand"
+	"strconv"
 	"strings"
 	"time"
 
-	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/types"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	apiv1 "kops/api/v1"
 	"kops/internal/azure"
 )
 
 const (
-	PollingInterval = 30 * time.Second
-	MaxRetries      = 3
-	RetryDelay      = 5 * time.Second
+	MaxRetries = 3
+	RetryDelay = 5 * time.Second
+
+	// DefaultHistoryRetention is how long a completed Operation CR sticks
+	// around before reapCompletedCRs deletes it, unless overridden per-CR
+	// by spec.ttlSecondsAfterFinished.
+	DefaultHistoryRetention = 24 * time.Hour
+	GCInterval              = 10 * time.Minute
+
+	// DefaultIdlePollInterval is how often the reconciler checks Azure
+	// when nothing is in progress. It's jittered by pollJitterFraction so
+	// a fleet of reconcilers started together doesn't poll in lockstep.
+	DefaultIdlePollInterval = 2 * time.Minute
+	pollJitterFraction      = 0.2
+
+	// DefaultActivePollInterval is how often the reconciler checks Azure
+	// once an operation is InProgress, so completion (and the narrow
+	// window where AbortClusterOperation would otherwise race a 409) is
+	// noticed quickly instead of waiting out the idle interval.
+	DefaultActivePollInterval = 5 * time.Second
+
+	// DefaultMaxConsecutiveFailures trips the circuit breaker, pausing
+	// syncs for CircuitBreakerCooldown instead of hammering Azure with
+	// requests that are already failing.
+	DefaultMaxConsecutiveFailures = 5
+	DefaultCircuitBreakerCooldown = 5 * time.Minute
 )
 
 type OperationReconciler struct {
-	Client        client.Client
+	Client client.Client
+	// APIReader is used for reads that immediately follow a write within
+	// the same sync pass (see findActiveCR), where a cached read could
+	// still miss the write. Defaults to Client if unset, so this is safe
+	// to leave nil against clientfake, which has no cache to lag behind.
+	APIReader     client.Reader
 	Azure         azure.AzureClientInterface
 	Namespace     string
 	ResourceGroup string
 	ClusterName   string
+	// FleetLabels are merged into every Operation CR this reconciler
+	// creates, on top of the azure.cluster.name/azure.resource.group/
+	// azure.operation.* labels below. A FleetReconciler uses this to stamp
+	// azure.subscription.id and azure.fleet.name so CRs from different
+	// fleet members/subscriptions can be told apart.
+	FleetLabels map[string]string
+	// HistoryRetention is how long a completed Operation CR is kept
+	// around for reapCompletedCRs before it's deleted. Defaults to
+	// DefaultHistoryRetention.
+	HistoryRetention time.Duration
+
+	// IdlePollInterval/ActivePollInterval/MaxConsecutiveFailures/
+	// CircuitBreakerCooldown tune monitoringLoop's two polling modes; see
+	// their Default* constants.
+	IdlePollInterval       time.Duration
+	ActivePollInterval     time.Duration
+	MaxConsecutiveFailures int
+	CircuitBreakerCooldown time.Duration
+
+	// PreflightChecks run at the top of every sync, before any Azure or
+	// Kubernetes work that assumes a healthy environment. Defaults to
+	// defaultPreflightChecks; a caller (or a future controller embedding
+	// the same pattern) can override or extend this list.
+	PreflightChecks []PreflightCheck
 
 	isRunning bool
 	stopCh    chan struct{}
+
+	// lastInProgress records whether the most recent sync found the
+	// cluster or any agent pool InProgress, so monitoringLoop knows
+	// whether to poll at ActivePollInterval or IdlePollInterval next.
+	lastInProgress bool
+	// preflightFailed records whether the most recent preflight check
+	// failed, so nextPollInterval requeues at preflightFailedRequeueAfter
+	// instead of waiting out the idle/active interval.
+	preflightFailed bool
+	// consecutiveFailures and circuitOpenUntil implement the circuit
+	// breaker: once consecutiveFailures reaches MaxConsecutiveFailures,
+	// syncs are skipped until circuitOpenUntil passes.
+	consecutiveFailures int
+	circuitOpenUntil    time.Time
 }
 
 type Config struct {
-	Namespace     string
-	ResourceGroup string
-	ClusterName   string
+	Namespace              string
+	ResourceGroup          string
+	ClusterName            string
+	FleetLabels            map[string]string
+	HistoryRetention       time.Duration
+	IdlePollInterval       time.Duration
+	ActivePollInterval     time.Duration
+	MaxConsecutiveFailures int
+	CircuitBreakerCooldown time.Duration
+	// PreflightChecks overrides the reconciler's default preflight check
+	// list. Leave nil to use defaultPreflightChecks.
+	PreflightChecks []PreflightCheck
+	// APIReader overrides the reconciler's post-write verification reads.
+	// Leave nil to read through Client (the common case: clientfake has no
+	// cache, so there's nothing to bypass).
+	APIReader client.Reader
 }
 
 func NewOperationReconciler(client client.Client, azureClient azure.AzureClientInterface, config Config) (*OperationReconciler, error) {
@@ -55,14 +139,44 @@ func NewOperationReconciler(client client.Client, azureClient azure.AzureClientI
 	if config.Namespace == "" {
 		config.Namespace = "default"
 	}
+	if config.HistoryRetention == 0 {
+		config.HistoryRetention = DefaultHistoryRetention
+	}
+	if config.IdlePollInterval == 0 {
+		config.IdlePollInterval = DefaultIdlePollInterval
+	}
+	if config.ActivePollInterval == 0 {
+		config.ActivePollInterval = DefaultActivePollInterval
+	}
+	if config.MaxConsecutiveFailures == 0 {
+		config.MaxConsecutiveFailures = DefaultMaxConsecutiveFailures
+	}
+	if config.CircuitBreakerCooldown == 0 {
+		config.CircuitBreakerCooldown = DefaultCircuitBreakerCooldown
+	}
+	if len(config.PreflightChecks) == 0 {
+		config.PreflightChecks = defaultPreflightChecks()
+	}
+	apiReader := config.APIReader
+	if apiReader == nil {
+		apiReader = client
+	}
 
 	return &OperationReconciler{
-		Client:        client,
-		Azure:         azureClient,
-		Namespace:     config.Namespace,
-		ResourceGroup: config.ResourceGroup,
-		ClusterName:   config.ClusterName,
-		stopCh:        make(chan struct{}),
+		Client:                 client,
+		APIReader:              apiReader,
+		Azure:                  azureClient,
+		Namespace:              config.Namespace,
+		ResourceGroup:          config.ResourceGroup,
+		ClusterName:            config.ClusterName,
+		FleetLabels:            config.FleetLabels,
+		HistoryRetention:       config.HistoryRetention,
+		IdlePollInterval:       config.IdlePollInterval,
+		ActivePollInterval:     config.ActivePollInterval,
+		MaxConsecutiveFailures: config.MaxConsecutiveFailures,
+		CircuitBreakerCooldown: config.CircuitBreakerCooldown,
+		PreflightChecks:        config.PreflightChecks,
+		stopCh:                 make(chan struct{}),
 	}, nil
 }
 
@@ -80,6 +194,7 @@ func (r *OperationReconciler) Start(ctx context.Context) error {
 
 	r.isRunning = true
 	go r.monitoringLoop(ctx)
+	go r.gcLoop(ctx)
 
 	klog.InfoS("Monitoring started")
 	return nil
@@ -105,13 +220,7 @@ func (r *OperationReconciler) validateConnections(ctx context.Context) error {
 	}
 
 	// Test Kubernetes - try to list in namespace
-	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "OperationList",
-	})
-
+	list := &apiv1.OperationList{}
 	if err := r.Client.List(testCtx, list, client.InNamespace(r.Namespace)); err != nil {
 		return fmt.Errorf("kubernetes test failed: %w", err)
 	}
@@ -119,41 +228,200 @@ func (r *OperationReconciler) validateConnections(ctx context.Context) error {
 	return nil
 }
 
+// monitoringLoop polls at ActivePollInterval while an operation is in
+// progress and at a jittered IdlePollInterval otherwise, rather than a
+// single fixed ticker, so idle clusters aren't polled needlessly and
+// in-progress ones are noticed finishing quickly.
 func (r *OperationReconciler) monitoringLoop(ctx context.Context) {
-	ticker := time.NewTicker(PollingInterval)
-	defer ticker.Stop()
-
 	// Initial sync
 	r.syncWithRetry(ctx)
 
 	for {
+		timer := time.NewTimer(r.nextPollInterval())
+
 		select {
-		case <-ticker.C:
+		case <-timer.C:
 			r.syncWithRetry(ctx)
 		case <-r.stopCh:
+			timer.Stop()
 			klog.InfoS("Monitoring loop stopped")
 			return
 		case <-ctx.Done():
+			timer.Stop()
 			klog.InfoS("Context done, stopping")
 			return
 		}
 	}
 }
 
+// nextPollInterval picks the delay before the next sync. A failed
+// preflight check takes priority over the idle/active split: there's no
+// point waiting out a multi-minute idle interval to retry a broken
+// credential when the environment might be fixed in seconds.
+func (r *OperationReconciler) nextPollInterval() time.Duration {
+	if r.preflightFailed {
+		return preflightFailedRequeueAfter
+	}
+	if r.lastInProgress {
+		return r.ActivePollInterval
+	}
+	return jitter(r.IdlePollInterval, pollJitterFraction)
+}
+
+// jitter returns base plus or minus up to fraction of itself, so a fleet
+// of reconcilers started at the same time don't all poll in lockstep.
+func jitter(base time.Duration, fraction float64) time.Duration {
+	delta := time.Duration(float64(base) * fraction)
+	if delta <= 0 {
+		return base
+	}
+	offset := time.Duration(rand.Int63n(int64(2*delta))) - delta
+	return base + offset
+}
+
+// gcLoop periodically reaps completed Operation CRs older than
+// HistoryRetention, the way a Job's ttlSecondsAfterFinished controller
+// would.
+func (r *OperationReconciler) gcLoop(ctx context.Context) {
+	ticker := time.NewTicker(GCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.reapCompletedCRs(ctx); err != nil {
+				klog.ErrorS(err, "GC failed")
+			}
+		case <-r.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reapCompletedCRs deletes Operation CRs that reached a terminal phase
+// more than HistoryRetention ago, honoring a per-CR
+// spec.ttlSecondsAfterFinished override when set.
+func (r *OperationReconciler) reapCompletedCRs(ctx context.Context) error {
+	list := &apiv1.OperationList{}
+
+	listOpts := []client.ListOption{
+		client.InNamespace(r.Namespace),
+		client.MatchingLabels{"azure.cluster.name": r.ClusterName},
+	}
+
+	if err := r.Client.List(ctx, list, listOpts...); err != nil {
+		return fmt.Errorf("failed to list CRs for GC: %w", err)
+	}
+
+	reaped := 0
+	for i := range list.Items {
+		item := &list.Items[i]
+
+		if item.Status.Phase == "" || item.Status.Phase == apiv1.PhaseInProgress {
+			continue
+		}
+		if item.Status.CompletionTime == nil {
+			continue
+		}
+
+		retention := r.HistoryRetention
+		if item.Spec.TTLSecondsAfterFinished != nil {
+			retention = time.Duration(*item.Spec.TTLSecondsAfterFinished) * time.Second
+		}
+
+		if time.Since(item.Status.CompletionTime.Time) < retention {
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, item); err != nil && !apierrors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to reap completed CR", "name", item.GetName())
+			continue
+		}
+		reaped++
+	}
+
+	if reaped > 0 {
+		klog.InfoS("Reaped completed Operation CRs", "count", reaped)
+	}
+	return nil
+}
+
 func (r *OperationReconciler) syncWithRetry(ctx context.Context) {
+	if !r.circuitOpenUntil.IsZero() && time.Now().Before(r.circuitOpenUntil) {
+		klog.V(2).InfoS("Circuit breaker open, skipping sync", "until", r.circuitOpenUntil)
+		return
+	}
+
+	if err := r.runPreflightChecks(ctx); err != nil {
+		klog.ErrorS(err, "Preflight checks failed, deferring sync")
+		r.preflightFailed = true
+		r.recordPreflightCondition(ctx, metav1.ConditionFalse, err.Error())
+		return
+	}
+	if r.preflightFailed {
+		r.preflightFailed = false
+		r.recordPreflightCondition(ctx, metav1.ConditionTrue, "preflight checks passed")
+	}
+
 	for attempt := 1; attempt <= MaxRetries; attempt++ {
-		if err := r.syncOperations(ctx); err != nil {
-			klog.ErrorS(err, "Sync failed", "attempt", attempt)
-			if attempt < MaxRetries {
-				time.Sleep(RetryDelay)
-				continue
-			}
-		} else {
+		err := r.syncOperations(ctx)
+		if err == nil {
+			r.consecutiveFailures = 0
 			return // Success
 		}
+
+		klog.ErrorS(err, "Sync failed", "attempt", attempt)
+		r.consecutiveFailures++
+
+		if r.consecutiveFailures >= r.MaxConsecutiveFailures {
+			r.circuitOpenUntil = time.Now().Add(r.CircuitBreakerCooldown)
+			klog.ErrorS(err, "Circuit breaker tripped after repeated failures",
+				"consecutiveFailures", r.consecutiveFailures, "cooldown", r.CircuitBreakerCooldown)
+			return
+		}
+
+		if attempt < MaxRetries {
+			// Context/Stop()-aware: a caller stopping the reconciler mid-sleep
+			// shouldn't have to wait out the backoff first.
+			if !r.sleepForRetry(ctx, retryDelayFor(err)) {
+				return
+			}
+		}
 	}
 }
 
+// sleepForRetry blocks for d or until ctx/Stop() fires, returning false in
+// the latter case so callers can abandon the retry loop immediately.
+func (r *OperationReconciler) sleepForRetry(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.stopCh:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryDelayFor prefers the Retry-After Azure returned in a throttling or
+// transient error over the fixed RetryDelay.
+func retryDelayFor(err error) time.Duration {
+	var respErr *azcore.ResponseError
+	if stderrors.As(err, &respErr) && respErr.RawResponse != nil {
+		if ra := respErr.RawResponse.Header.Get("Retry-After"); ra != "" {
+			if seconds, parseErr := strconv.Atoi(ra); parseErr == nil {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return RetryDelay
+}
+
 func (r *OperationReconciler) syncOperations(ctx context.Context) error {
 	// Get Azure status
 	azureCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -166,122 +434,340 @@ func (r *OperationReconciler) syncOperations(ctx context.Context) error {
 
 	klog.V(2).InfoS("Azure status", "inProgress", state.InProgress, "type", state.Type)
 
-	// Generate CR name
-	opName := r.generateOperationName(state)
-
-	// Check if CR exists
-	existing := &unstructured.Unstructured{}
-	existing.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
+	active, err := r.findActiveCR(ctx, client.MatchingLabels{
+		"azure.cluster.name":    r.ClusterName,
+		"azure.resource.group":  r.ResourceGroup,
+		"azure.operation.scope": "cluster",
 	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case active != nil && state.InProgress:
+		err = r.refreshActiveCR(ctx, active, state.Status)
+	case active != nil && !state.InProgress:
+		err = r.completeCR(ctx, active, state.Status)
+	case active == nil && state.InProgress:
+		name := r.generateOperationName(state.Type, "", state.OperationID)
+		err = r.createCR(ctx, name, state)
+	}
+	if err != nil {
+		return err
+	}
+
+	anyPoolInProgress, err := r.syncAgentPoolOperations(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Drives monitoringLoop's idle-vs-active polling cadence: any pool or
+	// the cluster itself being InProgress switches the next poll to
+	// ActivePollInterval.
+	r.lastInProgress = state.InProgress || anyPoolInProgress
+
+	return r.enforceAbortPolicies(ctx)
+}
+
+// syncAgentPoolOperations mirrors syncOperations, but per agent pool: a
+// pool can be mid-upgrade or mid-scale independently of the cluster and of
+// its sibling pools, which the single cluster-level ProvisioningState
+// can't represent. It reports whether any pool is still InProgress.
+func (r *OperationReconciler) syncAgentPoolOperations(ctx context.Context) (bool, error) {
+	azureCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	pools, err := r.Azure.GetAgentPoolOperationStatuses(azureCtx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get agent pool statuses: %w", err)
+	}
+
+	anyInProgress := false
+	for _, pool := range pools {
+		if pool.InProgress {
+			anyInProgress = true
+		}
+
+		active, err := r.findActiveCR(ctx, client.MatchingLabels{
+			"azure.cluster.name":    r.ClusterName,
+			"azure.resource.group":  r.ResourceGroup,
+			"azure.operation.scope": "agentpool",
+			"azure.agentpool.name":  pool.AgentPoolName,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case active != nil && pool.InProgress:
+			err = r.refreshActiveCR(ctx, active, pool.Status)
+		case active != nil && !pool.InProgress:
+			err = r.completeCR(ctx, active, pool.Status)
+		case active == nil && pool.InProgress:
+			name := r.generateOperationName(pool.Type, pool.AgentPoolName, pool.OperationID)
+			err = r.createAgentPoolCR(ctx, name, pool)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return anyInProgress, nil
+}
 
-	err = r.Client.Get(ctx, types.NamespacedName{
-		Name:      opName,
-		Namespace: r.Namespace,
-	}, existing)
+// findActiveCR returns the Operation matching selector that's still in the
+// InProgress phase, or nil if none is. Looking this up by label selector
+// rather than a fixed metadata.name means a brand new operation that starts
+// after a previous one of the same type finished creates a fresh Operation
+// instead of resurrecting the completed one. Callers must include
+// azure.operation.scope in selector - MatchingLabels is a subset match, so
+// a cluster-level selector missing it would also match agent-pool CRs,
+// which additionally carry azure.agentpool.name.
+// findActiveCR lists through APIReader rather than Client: it's called
+// right after createCR/completeCR within the same sync pass (and again on
+// the next poll), and a cached reader can still miss a write that recent -
+// see APIReader's doc comment.
+func (r *OperationReconciler) findActiveCR(ctx context.Context, selector client.MatchingLabels) (*apiv1.Operation, error) {
+	list := &apiv1.OperationList{}
+
+	if err := r.APIReader.List(ctx, list, client.InNamespace(r.Namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list Operations: %w", err)
+	}
 
-	exists := err == nil
-	if err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to check CR: %w", err)
+	for i := range list.Items {
+		if list.Items[i].Status.Phase == apiv1.PhaseInProgress {
+			return &list.Items[i], nil
+		}
 	}
 
-	// Core logic: simple binary state
-	if state.InProgress && !exists {
-		return r.createCR(ctx, opName, state)
-	} else if !state.InProgress && exists {
-		return r.deleteCR(ctx, existing)
+	return nil, nil
+}
+
+// refreshActiveCR re-stamps an ongoing operation's status with the latest
+// Azure state without changing its phase. Status is the only thing that
+// changes here, so it's a single status subresource update.
+func (r *OperationReconciler) refreshActiveCR(ctx context.Context, op *apiv1.Operation, azureStatus string) error {
+	now := metav1.Now()
+	op.Status.AzureStatus = azureStatus
+	op.Status.LastTransitionTime = &now
+
+	if err := r.Client.Status().Update(ctx, op); err != nil {
+		return fmt.Errorf("failed to refresh Operation %s: %w", op.GetName(), err)
 	}
 
 	return nil
 }
 
+// completeCR transitions an Operation from InProgress to a terminal phase
+// (Succeeded/Failed/Canceled, mapped from Azure's final provisioning state)
+// instead of deleting it, so reapCompletedCRs has a record to
+// garbage-collect on HistoryRetention rather than the Operation simply
+// vanishing. The azure.operation.status label is a metadata change, so it's
+// patched first via a plain Update; status fields are patched afterward via
+// a separate Status().Update call, so a watcher only waiting on status
+// never observes a half-written transition.
+func (r *OperationReconciler) completeCR(ctx context.Context, op *apiv1.Operation, finalAzureStatus string) error {
+	phase := terminalPhaseFor(finalAzureStatus)
+	now := metav1.Now()
+
+	labels := op.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels["azure.operation.status"] = "completed"
+	op.SetLabels(labels)
+
+	if err := r.Client.Update(ctx, op); err != nil {
+		return fmt.Errorf("failed to label completed Operation %s: %w", op.GetName(), err)
+	}
+
+	durationSeconds := 0.0
+	if op.Status.StartTime != nil {
+		durationSeconds = now.Sub(op.Status.StartTime.Time).Seconds()
+	}
+
+	op.Status.Phase = phase
+	op.Status.AzureStatus = finalAzureStatus
+	op.Status.LastTransitionTime = &now
+	op.Status.CompletionTime = &now
+	op.Status.DurationSeconds = durationSeconds
+
+	conditionType := apiv1.ConditionReady
+	conditionStatus := metav1.ConditionTrue
+	if phase == apiv1.PhaseFailed {
+		conditionType = apiv1.ConditionFailed
+		conditionStatus = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&op.Status.Conditions, metav1.Condition{
+		Type:    conditionType,
+		Status:  conditionStatus,
+		Reason:  phase,
+		Message: fmt.Sprintf("operation reached Azure provisioning state %q", finalAzureStatus),
+	})
+
+	if err := r.Client.Status().Update(ctx, op); err != nil {
+		return fmt.Errorf("failed to complete Operation %s: %w", op.GetName(), err)
+	}
+
+	klog.InfoS("Operation completed", "name", op.GetName(), "phase", phase)
+	return nil
+}
+
+// terminalPhaseFor maps an Azure provisioning state to the terminal
+// status.phase an Operation settles into once its operation stops being
+// InProgress.
+func terminalPhaseFor(provisioningState string) string {
+	switch strings.ToLower(provisioningState) {
+	case "succeeded":
+		return apiv1.PhaseSucceeded
+	case "failed":
+		return apiv1.PhaseFailed
+	case "canceled", "cancelled":
+		return apiv1.PhaseCanceled
+	default:
+		return apiv1.PhaseSucceeded
+	}
+}
+
 func (r *OperationReconciler) createCR(ctx context.Context, name string, state azure.OperationStatus) error {
-	klog.InfoS("Creating CR", "name", name)
-
-	cr := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"apiVersion": "core.kops.aks.microsoft.com/v1",
-			"kind":       "Operation",
-			"metadata": map[string]interface{}{
-				"name":      name,
-				"namespace": r.Namespace,
-				"labels": map[string]interface{}{
-					"azure.cluster.name":     r.ClusterName,
-					"azure.resource.group":   r.ResourceGroup,
-					"azure.operation.type":   state.Type,
-					"azure.operation.status": "in-progress",
-				},
-				"annotations": map[string]interface{}{
-					"azure.operation.id":      state.OperationID,
-					"azure.operation.started": time.Now().Format(time.RFC3339),
-				},
-			},
-			"spec": map[string]interface{}{
-				"clusterName":   r.ClusterName,
-				"resourceGroup": r.ResourceGroup,
-				"operationType": state.Type,
-				"operationID":   state.OperationID,
-				"azureStatus":   state.Status,
-			},
-			"status": map[string]interface{}{
-				"phase":       "InProgress",
-				"azureStatus": state.Status,
-				"lastChecked": time.Now().Format(time.RFC3339),
+	klog.InfoS("Creating Operation", "name", name)
+
+	labels := map[string]string{
+		"azure.cluster.name":     r.ClusterName,
+		"azure.resource.group":   r.ResourceGroup,
+		"azure.operation.scope":  "cluster",
+		"azure.operation.type":   state.Type,
+		"azure.operation.status": "in-progress",
+	}
+	for k, v := range r.FleetLabels {
+		labels[k] = v
+	}
+
+	now := metav1.Now()
+	op := &apiv1.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				"azure.operation.id":      state.OperationID,
+				"azure.operation.started": now.Format(time.RFC3339),
 			},
 		},
+		Spec: apiv1.OperationSpec{
+			ClusterName:   r.ClusterName,
+			ResourceGroup: r.ResourceGroup,
+			OperationType: state.Type,
+			OperationID:   state.OperationID,
+		},
+		Status: apiv1.OperationStatus{
+			Phase:              apiv1.PhaseInProgress,
+			AzureStatus:        state.Status,
+			StartTime:          &now,
+			LastTransitionTime: &now,
+		},
 	}
 
-	if err := r.Client.Create(ctx, cr); err != nil {
-		return fmt.Errorf("failed to create CR: %w", err)
+	if err := r.Client.Create(ctx, op); err != nil {
+		return fmt.Errorf("failed to create Operation: %w", err)
 	}
 
-	klog.InfoS("CR created", "name", name)
+	klog.InfoS("Operation created", "name", name)
 	return nil
 }
 
-func (r *OperationReconciler) deleteCR(ctx context.Context, cr *unstructured.Unstructured) error {
-	name := cr.GetName()
-	klog.InfoS("Deleting CR", "name", name)
+func (r *OperationReconciler) createAgentPoolCR(ctx context.Context, name string, pool azure.AgentPoolOperationStatus) error {
+	klog.InfoS("Creating agent pool Operation", "name", name, "agentPool", pool.AgentPoolName)
 
-	if err := r.Client.Delete(ctx, cr); err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete CR: %w", err)
+	labels := map[string]string{
+		"azure.cluster.name":     r.ClusterName,
+		"azure.resource.group":   r.ResourceGroup,
+		"azure.operation.scope":  "agentpool",
+		"azure.agentpool.name":   pool.AgentPoolName,
+		"azure.operation.type":   pool.Type,
+		"azure.operation.status": "in-progress",
+	}
+	for k, v := range r.FleetLabels {
+		labels[k] = v
 	}
 
-	klog.InfoS("CR deleted", "name", name)
-	return nil
-}
+	now := metav1.Now()
+	op := &apiv1.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				"azure.operation.id":      pool.OperationID,
+				"azure.operation.started": now.Format(time.RFC3339),
+			},
+		},
+		Spec: apiv1.OperationSpec{
+			ClusterName:   r.ClusterName,
+			ResourceGroup: r.ResourceGroup,
+			AgentPoolName: pool.AgentPoolName,
+			OperationType: pool.Type,
+			OperationID:   pool.OperationID,
+		},
+		Status: apiv1.OperationStatus{
+			Phase:              apiv1.PhaseInProgress,
+			AzureStatus:        pool.Status,
+			StartTime:          &now,
+			LastTransitionTime: &now,
+		},
+	}
 
-func (r *OperationReconciler) generateOperationName(state azure.OperationStatus) string {
-	cluster := strings.ToLower(r.ClusterName)
-	opType := strings.ToLower(state.Type)
+	if err := r.Client.Create(ctx, op); err != nil {
+		return fmt.Errorf("failed to create agent pool Operation: %w", err)
+	}
 
-	// Clean for Kubernetes
-	cluster = strings.ReplaceAll(cluster, ".", "-")
-	cluster = strings.ReplaceAll(cluster, "_", "-")
-	opType = strings.ReplaceAll(opType, ".", "-")
-	opType = strings.ReplaceAll(opType, "_", "-")
+	klog.InfoS("Agent pool Operation created", "name", name)
+	return nil
+}
 
-	name := fmt.Sprintf("azure-op-%s-%s", cluster, opType)
+// generateOperationName builds the Operation CR name from the cluster, the
+// (optional) agent pool, and the operation type, plus a short hash of the
+// Azure operationID. The hash suffix is what makes the name monotonic
+// across operation instances: a new operation that starts after a
+// previous one of the same type finished hashes to a different suffix, so
+// it gets a brand new CR instead of colliding with (and resurrecting) the
+// completed one. It also doubles as the overflow-truncation suffix when
+// the composed name exceeds Kubernetes' 63-character name limit.
+func (r *OperationReconciler) generateOperationName(opType, agentPoolName, operationID string) string {
+	cluster := sanitizeForName(r.ClusterName)
+	opType = sanitizeForName(opType)
+
+	sum := sha256.Sum256([]byte(operationID))
+	suffix := fmt.Sprintf("-%x", sum[:4])
+
+	var base string
+	if agentPoolName == "" {
+		base = fmt.Sprintf("azure-op-%s-%s", cluster, opType)
+	} else {
+		base = fmt.Sprintf("azure-op-%s-%s-%s", cluster, sanitizeForName(agentPoolName), opType)
+	}
 
-	// Truncate if too long
+	name := base + suffix
 	if len(name) > 63 {
-		name = name[:63]
+		name = base[:63-len(suffix)] + suffix
 	}
 
 	return name
 }
 
+// sanitizeForName lowercases s and replaces characters that aren't valid
+// in a Kubernetes resource name with hyphens.
+func sanitizeForName(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, ".", "-")
+	s = strings.ReplaceAll(s, "_", "-")
+	return s
+}
+
 func (r *OperationReconciler) CleanupOrphanedCRs(ctx context.Context) error {
 	klog.InfoS("Cleaning up orphaned CRs")
 
-	list := &unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "OperationList",
-	})
+	list := &apiv1.OperationList{}
 
 	listOpts := []client.ListOption{
 		client.InNamespace(r.Namespace),
@@ -293,8 +779,9 @@ func (r *OperationReconciler) CleanupOrphanedCRs(ctx context.Context) error {
 	}
 
 	deleted := 0
-	for _, item := range list.Items {
-		if err := r.Client.Delete(ctx, &item); err != nil && !errors.IsNotFound(err) {
+	for i := range list.Items {
+		item := &list.Items[i]
+		if err := r.Client.Delete(ctx, item); err != nil && !apierrors.IsNotFound(err) {
 			klog.ErrorS(err, "Failed to delete CR", "name", item.GetName())
 		} else {
 			deleted++
@@ -307,9 +794,11 @@ func (r *OperationReconciler) CleanupOrphanedCRs(ctx context.Context) error {
 
 func (r *OperationReconciler) GetStatus() map[string]interface{} {
 	return map[string]interface{}{
-		"running":         r.isRunning,
-		"cluster":         r.ClusterName,
-		"namespace":       r.Namespace,
-		"pollingInterval": PollingInterval.String(),
+		"running":            r.isRunning,
+		"cluster":            r.ClusterName,
+		"namespace":          r.Namespace,
+		"inProgress":         r.lastInProgress,
+		"idlePollInterval":   r.IdlePollInterval.String(),
+		"activePollInterval": r.ActivePollInterval.String(),
 	}
 }
@@ -1,427 +1,213 @@
-package controllers_test
+package controllers
 
 import (
 	"context"
 	"fmt"
-	"strings"
 	"testing"
-	"time"
 
-	"github.com/danekeahi/kops/controllers"
-	"github.com/danekeahi/kops/internal/azure"
 	"github.com/stretchr/testify/require"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	ctrl "sigs.k8s.io/controller-runtime"
-	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "kops/api/v1"
+	"kops/internal/azure"
+	"kops/internal/testutil"
 )
 
-// AzureClientInterface defines the interface that the Azure client should implement
-type AzureClientInterface interface {
-	GetClusterOperationStatus(ctx context.Context) (*azure.OperationStatus, error)
+// fakeAzureClient implements azure.AzureClientInterface with
+// caller-supplied cluster/agent-pool statuses, so tests can drive
+// syncOperations/syncAgentPoolOperations without a real Azure backend.
+type fakeAzureClient struct {
+	clusterStatus azure.OperationStatus
+	clusterErr    error
+	agentPools    []azure.AgentPoolOperationStatus
 }
 
-// fakeAzureClient mocks the Azure client behavior
-type fakeAzureClient struct {
-	fakeStatus *azure.OperationStatus
+func (f *fakeAzureClient) GetClusterOperationStatus(ctx context.Context) (azure.OperationStatus, error) {
+	return f.clusterStatus, f.clusterErr
 }
 
-func (f *fakeAzureClient) GetClusterOperationStatus(ctx context.Context) (*azure.OperationStatus, error) {
-	return f.fakeStatus, nil
+func (f *fakeAzureClient) GetAgentPoolOperationStatuses(ctx context.Context) ([]azure.AgentPoolOperationStatus, error) {
+	return f.agentPools, nil
 }
 
-// fakeAzureClientWithError mocks the Azure client that returns an error
-type fakeAzureClientWithError struct {
-	err error
+func (f *fakeAzureClient) GetAdminKubeconfig(ctx context.Context, clusterName, resourceGroup string) (string, error) {
+	return "", nil
 }
 
-func (f *fakeAzureClientWithError) GetClusterOperationStatus(ctx context.Context) (*azure.OperationStatus, error) {
-	return nil, f.err
+func (f *fakeAzureClient) TestConnection(ctx context.Context) error {
+	return f.clusterErr
 }
 
-func TestReconcile_CreateOperationCRWhenInProgress(t *testing.T) {
-	scheme := runtime.NewScheme()
+func (f *fakeAzureClient) AbortClusterOperation(ctx context.Context, reason string) error {
+	return nil
+}
 
-	// Fake Kubernetes client
-	k8sClient := clientfake.NewClientBuilder().WithScheme(scheme).Build()
+func (f *fakeAzureClient) AbortAgentPoolOperation(ctx context.Context, agentPoolName, reason string) error {
+	return nil
+}
 
-	// Fake Azure client returns an in-progress state
-	azureClient := &fakeAzureClient{
-		fakeStatus: &azure.OperationStatus{
-			InProgress:    true,
-			OperationType: "Updating",
-			Status:        "Updating",
-			OperationID:   "test-cluster-Updating",
-		},
-	}
+func newReconciler(t *testing.T, azureClient *fakeAzureClient) *OperationReconciler {
+	t.Helper()
+
+	k8sClient := testutil.NewFakeClientBuilder().Build()
 
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
+	r, err := NewOperationReconciler(k8sClient, azureClient, Config{
 		ResourceGroup: "test-rg",
 		ClusterName:   "test-cluster",
-	}
+	})
+	require.NoError(t, err)
+	return r
+}
 
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "op-test-cluster-updating", // Use generated name in lowercase
-			Namespace: "default",
+func TestSyncOperations_CreatesOperationWhenInProgress(t *testing.T) {
+	r := newReconciler(t, &fakeAzureClient{
+		clusterStatus: azure.OperationStatus{
+			InProgress:  true,
+			Type:        "Upgrading",
+			Status:      "Updating",
+			OperationID: "op-1",
 		},
-	}
-
-	// run reconcile
-	result, err := reconciler.Reconcile(context.TODO(), req)
-	require.NoError(t, err)
-	require.Equal(t, ctrl.Result{RequeueAfter: 30 * time.Second}, result, "Expected requeue after 30s for continuous monitoring")
-
-	// fetch the created object
-	created := &unstructured.Unstructured{}
-	created.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
 	})
 
-	err = k8sClient.Get(context.TODO(), req.NamespacedName, created)
-	require.NoError(t, err, "Expected Operation CR to be created")
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	// Verify CR content
-	spec, found, err := unstructured.NestedMap(created.Object, "spec")
+	active, err := r.findActiveCR(context.Background(), map[string]string{
+		"azure.cluster.name":   "test-cluster",
+		"azure.resource.group": "test-rg",
+	})
 	require.NoError(t, err)
-	require.True(t, found)
-	require.Equal(t, "Updating", spec["operationStatus"])
-	require.Equal(t, "test-cluster", spec["clusterName"])
+	require.NotNil(t, active)
+	require.Equal(t, apiv1.PhaseInProgress, active.Status.Phase)
+	require.Equal(t, "Upgrading", active.Spec.OperationType)
 }
 
-func TestReconcile_DeleteOperationCRWhenNotInProgress(t *testing.T) {
-	scheme := runtime.NewScheme()
-
-	// existing CR to be deleted
-	existing := &unstructured.Unstructured{}
-	existing.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
-	})
-	existing.SetName("op-test-cluster-Succeeded")
-	existing.SetNamespace("default")
-
-	k8sClient := clientfake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(existing). // inject pre-existing CR
-		Build()
-
+func TestSyncOperations_CompletesOperationWhenFinished(t *testing.T) {
 	azureClient := &fakeAzureClient{
-		fakeStatus: &azure.OperationStatus{
-			InProgress:    false,
-			OperationType: "",
-			Status:        "Succeeded",
-			OperationID:   "test-cluster-Succeeded",
+		clusterStatus: azure.OperationStatus{
+			InProgress:  true,
+			Type:        "Upgrading",
+			Status:      "Updating",
+			OperationID: "op-1",
 		},
 	}
+	r := newReconciler(t, azureClient)
 
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
-		ResourceGroup: "test-rg",
-		ClusterName:   "test-cluster",
-	}
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "op-test-cluster-Succeeded",
-			Namespace: "default",
-		},
+	azureClient.clusterStatus = azure.OperationStatus{
+		InProgress:  false,
+		Status:      "Succeeded",
+		OperationID: "op-1",
 	}
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	result, err := reconciler.Reconcile(context.TODO(), req)
-	require.NoError(t, err)
-	require.Equal(t, ctrl.Result{RequeueAfter: 30 * time.Second}, result, "Expected requeue after 30s for continuous monitoring")
-
-	// fetch again, should be deleted
-	created := &unstructured.Unstructured{}
-	created.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
+	active, err := r.findActiveCR(context.Background(), map[string]string{
+		"azure.cluster.name":   "test-cluster",
+		"azure.resource.group": "test-rg",
 	})
-	err = k8sClient.Get(context.TODO(), req.NamespacedName, created)
-	require.Error(t, err, "Expected Operation CR to be deleted")
-}
-
-func TestReconcile_AzureClientError(t *testing.T) {
-	scheme := runtime.NewScheme()
-	k8sClient := clientfake.NewClientBuilder().WithScheme(scheme).Build()
+	require.NoError(t, err)
+	require.Nil(t, active, "operation should no longer be InProgress")
 
-	// Azure client that returns an error
-	azureClient := &fakeAzureClientWithError{
-		err: fmt.Errorf("azure connection failed"),
-	}
+	list := &apiv1.OperationList{}
+	require.NoError(t, r.Client.List(context.Background(), list))
+	require.Len(t, list.Items, 1, "completed Operation should be kept around, not deleted")
 
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
-		ResourceGroup: "test-rg",
-		ClusterName:   "test-cluster",
-	}
+	completed := list.Items[0]
+	require.Equal(t, apiv1.PhaseSucceeded, completed.Status.Phase)
+	require.NotNil(t, completed.Status.CompletionTime)
+	require.Equal(t, "completed", completed.GetLabels()["azure.operation.status"])
 
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "test-op",
-			Namespace: "default",
-		},
-	}
-
-	// Should return error when Azure client fails and requeue after 30s
-	result, err := reconciler.Reconcile(context.TODO(), req)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "azure connection failed")
-	require.Equal(t, ctrl.Result{RequeueAfter: 30 * time.Second}, result, "Expected requeue after 30s even on error")
+	readyCondition := meta.FindStatusCondition(completed.Status.Conditions, apiv1.ConditionReady)
+	require.NotNil(t, readyCondition)
+	require.Equal(t, metav1.ConditionTrue, readyCondition.Status)
 }
 
-func TestReconcile_OperationAlreadyExistsWhenInProgress(t *testing.T) {
-	scheme := runtime.NewScheme()
-
-	// Pre-existing CR
-	existing := &unstructured.Unstructured{}
-	existing.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
-	})
-	existing.SetName("op-test-cluster-updating")
-	existing.SetNamespace("default")
-
-	k8sClient := clientfake.NewClientBuilder().
-		WithScheme(scheme).
-		WithObjects(existing).
-		Build()
-
-	// Azure client returns in-progress state
+func TestSyncOperations_RefreshesActiveOperation(t *testing.T) {
 	azureClient := &fakeAzureClient{
-		fakeStatus: &azure.OperationStatus{
-			InProgress:    true,
-			OperationType: "Updating",
-			Status:        "Updating",
-			OperationID:   "test-cluster-Updating",
+		clusterStatus: azure.OperationStatus{
+			InProgress:  true,
+			Type:        "Upgrading",
+			Status:      "Updating",
+			OperationID: "op-1",
 		},
 	}
+	r := newReconciler(t, azureClient)
 
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
-		ResourceGroup: "test-rg",
-		ClusterName:   "test-cluster",
-	}
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "op-test-cluster-updating",
-			Namespace: "default",
-		},
-	}
+	azureClient.clusterStatus.Status = "Provisioning"
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	// Should not error when CR already exists and operation is in progress
-	result, err := reconciler.Reconcile(context.TODO(), req)
-	require.NoError(t, err)
-	require.Equal(t, ctrl.Result{RequeueAfter: 30 * time.Second}, result, "Expected requeue after 30s for continuous monitoring")
-
-	// Verify the CR still exists
-	fetched := &unstructured.Unstructured{}
-	fetched.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
-	})
-	err = k8sClient.Get(context.TODO(), req.NamespacedName, fetched)
-	require.NoError(t, err)
+	list := &apiv1.OperationList{}
+	require.NoError(t, r.Client.List(context.Background(), list))
+	require.Len(t, list.Items, 1, "refresh should not create a second Operation")
+	require.Equal(t, "Provisioning", list.Items[0].Status.AzureStatus)
 }
 
-func TestReconcile_NoOperationWhenNotInProgress(t *testing.T) {
-	scheme := runtime.NewScheme()
-	k8sClient := clientfake.NewClientBuilder().WithScheme(scheme).Build()
-
-	// Azure client returns not in-progress state
-	azureClient := &fakeAzureClient{
-		fakeStatus: &azure.OperationStatus{
-			InProgress:    false,
-			OperationType: "",
-			Status:        "Succeeded",
-			OperationID:   "test-cluster-Succeeded",
-		},
-	}
-
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
-		ResourceGroup: "test-rg",
-		ClusterName:   "test-cluster",
-	}
-
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "op-test-cluster-Succeeded",
-			Namespace: "default",
-		},
-	}
-
-	// Should not error when no CR exists and operation is not in progress
-	result, err := reconciler.Reconcile(context.TODO(), req)
-	require.NoError(t, err)
-	require.Equal(t, ctrl.Result{RequeueAfter: 30 * time.Second}, result, "Expected requeue after 30s for continuous monitoring")
-
-	// Verify no CR was created
-	fetched := &unstructured.Unstructured{}
-	fetched.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
+func TestSyncOperations_AzureError(t *testing.T) {
+	r := newReconciler(t, &fakeAzureClient{
+		clusterErr: fmt.Errorf("azure connection failed"),
 	})
-	err = k8sClient.Get(context.TODO(), req.NamespacedName, fetched)
-	require.Error(t, err, "Expected no Operation CR to exist")
+
+	err := r.syncOperations(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "azure connection failed")
 }
 
-func TestReconcile_DifferentOperationStates(t *testing.T) {
-	testCases := []struct {
-		name             string
-		status           string
-		expectInProgress bool
-	}{
-		{"Running state", "Running", true},
-		{"Updating state", "Updating", true},
-		{"Succeeded state", "Succeeded", false},
-		{"Failed state", "Failed", false},
-		{"Unknown state", "SomeUnknownState", false},
-	}
+func TestGenerateOperationName_StableForSameOperationID(t *testing.T) {
+	r := &OperationReconciler{ClusterName: "test-cluster"}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			scheme := runtime.NewScheme()
-			k8sClient := clientfake.NewClientBuilder().WithScheme(scheme).Build()
-
-			azureClient := &fakeAzureClient{
-				fakeStatus: &azure.OperationStatus{
-					InProgress:    tc.expectInProgress,
-					OperationType: tc.status,
-					Status:        tc.status,
-					OperationID:   fmt.Sprintf("test-cluster-%s", tc.status),
-				},
-			}
-
-			reconciler := &controllers.OperationReconciler{
-				Client:        k8sClient,
-				Azure:         azureClient,
-				ResourceGroup: "test-rg",
-				ClusterName:   "test-cluster",
-			}
-
-			// Use generated operation name instead of request name (ensure lowercase)
-			expectedOpName := strings.ToLower(fmt.Sprintf("op-test-cluster-%s", tc.status))
-			req := ctrl.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      expectedOpName,
-					Namespace: "default",
-				},
-			}
-
-			_, err := reconciler.Reconcile(context.TODO(), req)
-			require.NoError(t, err)
-
-			// Check if CR exists based on expected state
-			fetched := &unstructured.Unstructured{}
-			fetched.SetGroupVersionKind(schema.GroupVersionKind{
-				Group:   "core.kops.aks.microsoft.com",
-				Version: "v1",
-				Kind:    "Operation",
-			})
-			err = k8sClient.Get(context.TODO(), req.NamespacedName, fetched)
-
-			if tc.expectInProgress {
-				require.NoError(t, err, "Expected Operation CR to be created for in-progress state")
-			} else {
-				require.Error(t, err, "Expected no Operation CR for non-in-progress state")
-			}
-		})
-	}
-}
+	name1 := r.generateOperationName("Upgrading", "", "op-1")
+	name2 := r.generateOperationName("Upgrading", "", "op-1")
+	require.Equal(t, name1, name2)
 
-func TestReconcile_WithNamespace(t *testing.T) {
-	scheme := runtime.NewScheme()
-	k8sClient := clientfake.NewClientBuilder().WithScheme(scheme).Build()
+	name3 := r.generateOperationName("Upgrading", "", "op-2")
+	require.NotEqual(t, name1, name3, "a different operationID should never resurrect a prior Operation's name")
+}
 
+func TestSyncOperations_ClusterIdleDoesNotCompleteInProgressPool(t *testing.T) {
 	azureClient := &fakeAzureClient{
-		fakeStatus: &azure.OperationStatus{
-			InProgress:    true,
-			OperationType: "Updating",
-			Status:        "Updating",
-			OperationID:   "test-cluster-Updating",
+		clusterStatus: azure.OperationStatus{InProgress: false, Status: "Succeeded"},
+		agentPools: []azure.AgentPoolOperationStatus{
+			{AgentPoolName: "pool-1", InProgress: true, Type: "Scaling", Status: "Scaling", OperationID: "op-pool-1"},
 		},
 	}
+	r := newReconciler(t, azureClient)
 
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
-		ResourceGroup: "test-rg",
-		ClusterName:   "test-cluster",
-	}
-
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "op-test-cluster-updating",
-			Namespace: "test-namespace",
-		},
-	}
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	// Should handle request with namespace gracefully
-	_, err := reconciler.Reconcile(context.TODO(), req)
+	active, err := r.findActiveCR(context.Background(), map[string]string{
+		"azure.cluster.name":   "test-cluster",
+		"azure.resource.group": "test-rg",
+		"azure.agentpool.name": "pool-1",
+	})
 	require.NoError(t, err)
+	require.NotNil(t, active, "pool's InProgress Operation should survive a cluster-idle sync")
+	require.Equal(t, apiv1.PhaseInProgress, active.Status.Phase)
 
-	// Verify the CR was created in default namespace (controller uses default)
-	created := &unstructured.Unstructured{}
-	created.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   "core.kops.aks.microsoft.com",
-		Version: "v1",
-		Kind:    "Operation",
-	})
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	err = k8sClient.Get(context.TODO(), types.NamespacedName{
-		Name:      "op-test-cluster-updating",
-		Namespace: "default",
-	}, created)
-	require.NoError(t, err, "Expected Operation CR to be created")
+	list := &apiv1.OperationList{}
+	require.NoError(t, r.Client.List(context.Background(), list))
+	require.Len(t, list.Items, 1, "a second sync pass should refresh the pool's CR, not create a duplicate")
 }
 
-func TestReconcile_RequeuesWhenNotInProgress(t *testing.T) {
-	scheme := runtime.NewScheme()
-	k8sClient := clientfake.NewClientBuilder().WithScheme(scheme).Build()
-
-	azureClient := &fakeAzureClient{
-		fakeStatus: &azure.OperationStatus{
-			InProgress:    false,
-			OperationType: "",
-			Status:        "Succeeded",
-			OperationID:   "test-cluster-Succeeded",
-		},
-	}
-
-	reconciler := &controllers.OperationReconciler{
-		Client:        k8sClient,
-		Azure:         azureClient,
-		ResourceGroup: "test-rg",
-		ClusterName:   "test-cluster",
-	}
+func TestSyncOperations_CreatedOperationRoundTripsByNamespacedName(t *testing.T) {
+	r := newReconciler(t, &fakeAzureClient{
+		clusterStatus: azure.OperationStatus{InProgress: true, Type: "Upgrading", OperationID: "op-1"},
+	})
+	require.NoError(t, r.syncOperations(context.Background()))
 
-	req := ctrl.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "op-test-cluster-Succeeded",
-			Namespace: "default",
-		},
-	}
+	list := &apiv1.OperationList{}
+	require.NoError(t, r.Client.List(context.Background(), list))
+	require.Len(t, list.Items, 1)
 
-	// Should return RequeueAfter when not in progress
-	result, err := reconciler.Reconcile(context.TODO(), req)
-	require.NoError(t, err)
-	require.Equal(t, 30*time.Second, result.RequeueAfter, "Expected RequeueAfter to be 30 seconds for continuous monitoring")
+	var fetched apiv1.Operation
+	require.NoError(t, r.Client.Get(context.Background(), types.NamespacedName{
+		Name:      list.Items[0].Name,
+		Namespace: "default",
+	}, &fetched))
+	require.Equal(t, apiv1.PhaseInProgress, fetched.Status.Phase)
 }
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicinformer "k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// operationMonitorWorkers is how many goroutines drain
+// ControllerContext.operationQueue. Rule evaluation is cheap - two
+// ConfigMap reads, compiling rules.json, and a handful of CEL
+// evaluations - so a small fixed
+// pool keeps up even with many Operations in flight.
+const operationMonitorWorkers = 2
+
+// operationMonitor is the operation-monitor controller: it watches the
+// Operation CRD and works ControllerContext's operationQueue, replacing
+// the old one-goroutine-per-CR model with a fixed pool of workers pulling
+// operation names off a shared queue.
+type operationMonitor struct {
+	ctx *ControllerContext
+
+	mu    sync.Mutex
+	state map[string]*operationMonitorState
+}
+
+// operationMonitorState is what operation-monitor keeps per Operation: its
+// hysteresis window, and whether an abort has already been dispatched for
+// it, so the controller stops re-evaluating it until the CR is deleted and
+// recreated.
+type operationMonitorState struct {
+	evaluator *RuleEvaluator
+	aborted   bool
+}
+
+// startOperationMonitor is operation-monitor's StartControllerFunc.
+func startOperationMonitor(ctx *ControllerContext) error {
+	m := &operationMonitor{ctx: ctx, state: make(map[string]*operationMonitorState)}
+
+	dynFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(ctx.DynClient, time.Minute*10, "default", nil)
+	opInformer := dynFactory.ForResource(operationGVR).Informer()
+	opInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			name := obj.(*unstructured.Unstructured).GetName()
+			fmt.Printf("Operation CR created: %s\n", name)
+			ctx.operations.add(name)
+			ctx.Metrics.SetActiveOperations(len(ctx.operations.list()))
+			ctx.operationQueue.Add(name)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			ctx.operationQueue.Add(newObj.(*unstructured.Unstructured).GetName())
+		},
+		DeleteFunc: func(obj interface{}) {
+			name := obj.(*unstructured.Unstructured).GetName()
+			fmt.Printf("Operation CR deleted: %s\n", name)
+			ctx.operations.remove(name)
+			ctx.Metrics.SetActiveOperations(len(ctx.operations.list()))
+			m.mu.Lock()
+			delete(m.state, name)
+			m.mu.Unlock()
+		},
+	})
+
+	stop := make(chan struct{})
+	go dynFactory.Start(stop)
+	if !cache.WaitForCacheSync(stop, opInformer.HasSynced) {
+		return fmt.Errorf("operation-monitor: failed to sync operation informer cache")
+	}
+	ctx.markControllerSynced("operation-monitor")
+
+	for i := 0; i < operationMonitorWorkers; i++ {
+		go m.runWorker()
+	}
+	return nil
+}
+
+func (m *operationMonitor) runWorker() {
+	for m.processNextItem() {
+	}
+}
+
+func (m *operationMonitor) processNextItem() bool {
+	key, shutdown := m.ctx.operationQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer m.ctx.operationQueue.Done(key)
+
+	opName := key.(string)
+	if err := m.check(opName); err != nil {
+		fmt.Printf("operation-monitor: requeuing %s: %v\n", opName, err)
+		m.ctx.operationQueue.AddRateLimited(key)
+		return true
+	}
+	m.ctx.operationQueue.Forget(key)
+	return true
+}
+
+func (m *operationMonitor) stateFor(opName string) *operationMonitorState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.state[opName]
+	if !ok {
+		st = &operationMonitorState{evaluator: NewRuleEvaluator()}
+		m.state[opName] = st
+	}
+	return st
+}
+
+// check evaluates every rule in the metric-thresholds ConfigMap against
+// the current metrics snapshot for opName, records an Event per violation,
+// and - once a violation has sustained long enough - enqueues an
+// abortRequest rather than calling Azure directly, so a slow
+// AbortClusterOperation call can't stall evaluation of every other
+// Operation behind it.
+func (m *operationMonitor) check(opName string) error {
+	state := m.stateFor(opName)
+	if state.aborted {
+		return nil
+	}
+
+	metricCM, err := m.ctx.TypedClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "metrics-store", metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch metrics-store: %w", err)
+	}
+
+	rawMetrics := metricCM.Data["current_metrics.json"]
+	if rawMetrics == "" {
+		fmt.Println("current_metrics.json not found in ConfigMap.")
+		return nil
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(rawMetrics), &parsed); err != nil {
+		return fmt.Errorf("failed to parse current_metrics.json: %w", err)
+	}
+
+	thresholdCM, err := m.ctx.BaseClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "metric-thresholds", metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch metric-thresholds ConfigMap: %w", err)
+	}
+
+	// rules.json replaces the old flat thresholds.json - see RuleSet for
+	// the schema.
+	rawRules := thresholdCM.Data["rules.json"]
+	if rawRules == "" {
+		fmt.Println("rules.json not found in metric-thresholds ConfigMap.")
+		return nil
+	}
+
+	ruleSet, err := NewRuleSet([]byte(rawRules))
+	if err != nil {
+		return fmt.Errorf("failed to load metric rules: %w", err)
+	}
+
+	violations := ruleSet.Evaluate(parsed)
+
+	// Only a rule that's been sustained across ConsecutiveSamples
+	// evaluations or MinDuration actually requests an abort - a single
+	// spiky sample just builds up the evaluator's window. Observe runs
+	// even when nothing matched, so a clean round resets every window.
+	sustained := state.evaluator.Observe(ruleSet, violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	reportViolations(violations)
+
+	for _, v := range violations {
+		m.ctx.Recorder.Eventf(operationRef(opName), corev1.EventTypeWarning, "ThresholdViolated", "[%s] %s", v.RuleName, v.Message)
+		m.ctx.Metrics.ObserveViolation(v.RuleName, v.Severity)
+	}
+
+	var clusterReasons []string
+	poolReasons := make(map[string][]string)
+	for _, v := range sustained {
+		if v.Severity != SeverityAbort {
+			continue
+		}
+		if v.AgentPool != "" {
+			poolReasons[v.AgentPool] = append(poolReasons[v.AgentPool], v.Reason)
+		} else {
+			clusterReasons = append(clusterReasons, v.Reason)
+		}
+	}
+
+	for pool, reasons := range poolReasons {
+		m.ctx.abortQueue.Add(abortRequest{opName: opName, pool: pool, reason: strings.Join(reasons, ",")})
+	}
+	if len(clusterReasons) > 0 {
+		m.ctx.abortQueue.Add(abortRequest{opName: opName, reason: strings.Join(clusterReasons, ",")})
+	}
+
+	abortRequested := len(poolReasons) > 0 || len(clusterReasons) > 0
+	if abortRequested {
+		state.aborted = true
+	}
+	updateOperationStatus(m.ctx.DynClient, opName, parsed, violations, abortRequested, false)
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Condition types this monitor reports on the Operation CR's status. Their
+// meaning mirrors metav1.Condition's usual contract: Status reflects
+// whether the condition currently holds, Reason/Message explain why.
+const (
+	ConditionMonitoring        = "Monitoring"
+	ConditionThresholdViolated = "ThresholdViolated"
+	ConditionAbortRequested    = "AbortRequested"
+	ConditionAbortSucceeded    = "AbortSucceeded"
+)
+
+// operationRef builds the ObjectReference EventRecorder needs to attach an
+// Event to the named Operation CR, without an extra API round trip to fetch
+// the object just to read back its own identity.
+func operationRef(opName string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:       "Operation",
+		APIVersion: operationGVR.GroupVersion().String(),
+		Name:       opName,
+		Namespace:  "default",
+	}
+}
+
+// operationConditions reads status.conditions off obj into typed
+// metav1.Condition values, so meta.SetStatusCondition's transition-time and
+// already-set bookkeeping can be reused instead of reimplemented by hand
+// against the raw unstructured map.
+func operationConditions(obj *unstructured.Unstructured) ([]metav1.Condition, error) {
+	raw, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []metav1.Condition
+	if err := json.Unmarshal(data, &conditions); err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+// setOperationConditions is operationConditions in reverse: it writes
+// typed conditions back onto obj's status.conditions as the plain
+// map[string]interface{} the dynamic client expects.
+func setOperationConditions(obj *unstructured.Unstructured, conditions []metav1.Condition) error {
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return err
+	}
+
+	var raw []interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	return unstructured.SetNestedSlice(obj.Object, raw, "status", "conditions")
+}
+
+// updateOperationStatus patches the Operation CR's /status subresource with
+// the Monitoring/ThresholdViolated/AbortRequested/AbortSucceeded conditions,
+// a snapshot of the metrics just evaluated, and the list of
+// currently-firing violations. This is what makes `kubectl describe
+// operation` the source of truth for this subsystem instead of its
+// fmt.Printf logging.
+func updateOperationStatus(dynClient dynamic.Interface, opName string, parsed map[string]interface{}, violations []ThresholdViolation, abortRequested, abortSucceeded bool) {
+	obj, err := dynClient.Resource(operationGVR).Namespace("default").Get(context.TODO(), opName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Printf("Failed to fetch Operation %s to update status: %v\n", opName, err)
+		return
+	}
+
+	conditions, err := operationConditions(obj)
+	if err != nil {
+		fmt.Printf("Failed to read existing conditions on Operation %s: %v\n", opName, err)
+	}
+
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:    ConditionMonitoring,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MetricsObserved",
+		Message: "Health monitoring is actively evaluating rules against this operation",
+	})
+
+	violatedStatus, violatedReason, violatedMessage := metav1.ConditionFalse, "NoViolations", "No rule violations observed"
+	if len(violations) > 0 {
+		names := make([]string, 0, len(violations))
+		for _, v := range violations {
+			names = append(names, v.RuleName)
+		}
+		violatedStatus = metav1.ConditionTrue
+		violatedReason = "RuleMatched"
+		violatedMessage = fmt.Sprintf("%d rule(s) currently firing: %s", len(violations), strings.Join(names, ", "))
+	}
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:    ConditionThresholdViolated,
+		Status:  violatedStatus,
+		Reason:  violatedReason,
+		Message: violatedMessage,
+	})
+
+	if abortRequested {
+		meta.SetStatusCondition(&conditions, metav1.Condition{
+			Type:    ConditionAbortRequested,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SustainedViolation",
+			Message: "A sustained rule violation triggered an abort request",
+		})
+
+		abortStatus, abortReason := metav1.ConditionFalse, "AbortFailed"
+		if abortSucceeded {
+			abortStatus, abortReason = metav1.ConditionTrue, "AbortSucceeded"
+		}
+		meta.SetStatusCondition(&conditions, metav1.Condition{
+			Type:   ConditionAbortSucceeded,
+			Status: abortStatus,
+			Reason: abortReason,
+		})
+	}
+
+	if err := setOperationConditions(obj, conditions); err != nil {
+		fmt.Printf("Failed to set conditions on Operation %s: %v\n", opName, err)
+		return
+	}
+
+	violationMessages := make([]interface{}, 0, len(violations))
+	for _, v := range violations {
+		violationMessages = append(violationMessages, fmt.Sprintf("[%s] %s", v.RuleName, v.Message))
+	}
+	if err := unstructured.SetNestedSlice(obj.Object, violationMessages, "status", "violations"); err != nil {
+		fmt.Printf("Failed to set violations on Operation %s: %v\n", opName, err)
+		return
+	}
+
+	if metricsJSON, err := json.Marshal(parsed); err != nil {
+		fmt.Printf("Failed to marshal metrics snapshot for Operation %s: %v\n", opName, err)
+	} else if err := unstructured.SetNestedField(obj.Object, string(metricsJSON), "status", "lastObservedMetrics"); err != nil {
+		fmt.Printf("Failed to set lastObservedMetrics on Operation %s: %v\n", opName, err)
+	}
+
+	if _, err := dynClient.Resource(operationGVR).Namespace("default").UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{}); err != nil {
+		fmt.Printf("Failed to update Operation %s status: %v\n", opName, err)
+	}
+}
+
+// updateAbortStatus patches just the AbortSucceeded condition once
+// abort-executor has actually attempted the Azure call. It leaves
+// ThresholdViolated and the metrics snapshot alone - those reflect the
+// evaluation that produced the abort request, not its eventual outcome,
+// and abort-executor has neither on hand by the time it runs.
+func updateAbortStatus(dynClient dynamic.Interface, opName string, abortSucceeded bool) {
+	obj, err := dynClient.Resource(operationGVR).Namespace("default").Get(context.TODO(), opName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Printf("Failed to fetch Operation %s to update abort status: %v\n", opName, err)
+		return
+	}
+
+	conditions, err := operationConditions(obj)
+	if err != nil {
+		fmt.Printf("Failed to read existing conditions on Operation %s: %v\n", opName, err)
+	}
+
+	abortStatus, abortReason := metav1.ConditionFalse, "AbortFailed"
+	if abortSucceeded {
+		abortStatus, abortReason = metav1.ConditionTrue, "AbortSucceeded"
+	}
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:   ConditionAbortSucceeded,
+		Status: abortStatus,
+		Reason: abortReason,
+	})
+
+	if err := setOperationConditions(obj, conditions); err != nil {
+		fmt.Printf("Failed to set conditions on Operation %s: %v\n", opName, err)
+		return
+	}
+
+	if _, err := dynClient.Resource(operationGVR).Namespace("default").UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{}); err != nil {
+		fmt.Printf("Failed to update Operation %s status: %v\n", opName, err)
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "kops/api/v1"
+)
+
+// preflightFailedRequeueAfter is how soon monitoringLoop retries after a
+// preflight check fails, instead of waiting out the (much longer) idle
+// poll interval - a broken credential or unreachable endpoint is
+// usually fixed by an operator in seconds to minutes, not whatever the
+// normal polling cadence happens to be.
+const preflightFailedRequeueAfter = 5 * time.Second
+
+// PreflightCheck is one precondition OperationReconciler verifies before
+// every sync. Checks run in order and stop at the first failure, so the
+// reported error is unambiguous about which precondition broke. The list
+// is pluggable via Config.PreflightChecks/OperationReconciler.PreflightChecks
+// so another controller embedding this same polling pattern can register
+// its own checks instead of this package hard-coding every caller's needs.
+type PreflightCheck struct {
+	Name string
+	Run  func(ctx context.Context, r *OperationReconciler) error
+}
+
+// defaultPreflightChecks covers the failure modes that otherwise surface
+// confusingly deep inside syncOperations: a bad/expired Azure credential,
+// an unreachable or deleted cluster, missing RBAC for admin kubeconfig
+// retrieval, and an unreachable management cluster API server.
+func defaultPreflightChecks() []PreflightCheck {
+	return []PreflightCheck{
+		{
+			Name: "azure-credential",
+			Run: func(ctx context.Context, r *OperationReconciler) error {
+				return r.Azure.TestConnection(ctx)
+			},
+		},
+		{
+			Name: "cluster-reachable",
+			Run: func(ctx context.Context, r *OperationReconciler) error {
+				_, err := r.Azure.GetClusterOperationStatus(ctx)
+				return err
+			},
+		},
+		{
+			Name: "admin-credential-rbac",
+			Run: func(ctx context.Context, r *OperationReconciler) error {
+				_, err := r.Azure.GetAdminKubeconfig(ctx, r.ClusterName, r.ResourceGroup)
+				return err
+			},
+		},
+		{
+			Name: "kubernetes-reachable",
+			Run: func(ctx context.Context, r *OperationReconciler) error {
+				list := &apiv1.OperationList{}
+				return r.Client.List(ctx, list, client.InNamespace(r.Namespace))
+			},
+		},
+	}
+}
+
+// runPreflightChecks runs every registered check in order, returning the
+// first failure. A 10s timeout is applied per check so one hung dependency
+// can't block the whole preflight pass indefinitely.
+func (r *OperationReconciler) runPreflightChecks(ctx context.Context) error {
+	for _, check := range r.PreflightChecks {
+		checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := check.Run(checkCtx, r)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("preflight check %q failed: %w", check.Name, err)
+		}
+	}
+	return nil
+}
+
+// recordPreflightCondition stamps PreflightSucceeded onto whichever
+// Operation is currently InProgress for this cluster, best-effort: if none
+// exists yet (e.g. the very first sync after a bad credential was fixed
+// fails before any Operation was ever created), there's nothing to attach
+// the condition to and this is a no-op beyond the log line.
+func (r *OperationReconciler) recordPreflightCondition(ctx context.Context, status metav1.ConditionStatus, message string) {
+	active, err := r.findActiveCR(ctx, client.MatchingLabels{
+		"azure.cluster.name":    r.ClusterName,
+		"azure.resource.group":  r.ResourceGroup,
+		"azure.operation.scope": "cluster",
+	})
+	if err != nil || active == nil {
+		return
+	}
+
+	reason := "PreflightPassed"
+	if status == metav1.ConditionFalse {
+		reason = "PreflightFailed"
+	}
+
+	meta.SetStatusCondition(&active.Status.Conditions, metav1.Condition{
+		Type:    apiv1.ConditionPreflightSucceeded,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+
+	if err := r.Client.Status().Update(ctx, active); err != nil {
+		klog.ErrorS(err, "Failed to record preflight condition", "operation", active.GetName())
+	}
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "kops/api/v1"
+	"kops/internal/azure"
+	"kops/internal/testutil"
+)
+
+// preflightAzureClient embeds fakeAzureClient and lets tests force a
+// specific named check to fail, independent of GetClusterOperationStatus's
+// own return value (which syncOperations also depends on).
+type preflightAzureClient struct {
+	fakeAzureClient
+	failCheck string
+}
+
+func (f *preflightAzureClient) TestConnection(ctx context.Context) error {
+	if f.failCheck == "azure-credential" {
+		return fmt.Errorf("credential expired")
+	}
+	return nil
+}
+
+func (f *preflightAzureClient) GetAdminKubeconfig(ctx context.Context, clusterName, resourceGroup string) (string, error) {
+	if f.failCheck == "admin-credential-rbac" {
+		return "", fmt.Errorf("rbac denied")
+	}
+	return "", nil
+}
+
+func newPreflightReconciler(t *testing.T, azureClient *preflightAzureClient) *OperationReconciler {
+	t.Helper()
+
+	k8sClient := testutil.NewFakeClientBuilder().Build()
+
+	r, err := NewOperationReconciler(k8sClient, azureClient, Config{
+		ResourceGroup: "test-rg",
+		ClusterName:   "test-cluster",
+	})
+	require.NoError(t, err)
+	return r
+}
+
+func TestRunPreflightChecks_PassesWhenAllChecksPass(t *testing.T) {
+	r := newPreflightReconciler(t, &preflightAzureClient{})
+	require.NoError(t, r.runPreflightChecks(context.Background()))
+}
+
+func TestRunPreflightChecks_ReportsFailingCheckByName(t *testing.T) {
+	r := newPreflightReconciler(t, &preflightAzureClient{failCheck: "azure-credential"})
+
+	err := r.runPreflightChecks(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "azure-credential")
+	require.Contains(t, err.Error(), "credential expired")
+}
+
+func TestSyncWithRetry_PreflightFailureShortensNextPoll(t *testing.T) {
+	azureClient := &preflightAzureClient{failCheck: "admin-credential-rbac"}
+	r := newPreflightReconciler(t, azureClient)
+
+	r.syncWithRetry(context.Background())
+
+	require.True(t, r.preflightFailed)
+	require.Equal(t, preflightFailedRequeueAfter, r.nextPollInterval())
+}
+
+func TestSyncWithRetry_PreflightRecoveryRestoresNormalPolling(t *testing.T) {
+	azureClient := &preflightAzureClient{failCheck: "admin-credential-rbac"}
+	r := newPreflightReconciler(t, azureClient)
+
+	r.syncWithRetry(context.Background())
+	require.True(t, r.preflightFailed)
+
+	azureClient.failCheck = ""
+	azureClient.fakeAzureClient.clusterStatus = azure.OperationStatus{InProgress: false, Status: "Succeeded"}
+	r.syncWithRetry(context.Background())
+
+	require.False(t, r.preflightFailed)
+	require.NotEqual(t, preflightFailedRequeueAfter, r.nextPollInterval())
+}
+
+func TestRecordPreflightCondition_SetsConditionOnActiveOperation(t *testing.T) {
+	azureClient := &preflightAzureClient{}
+	azureClient.fakeAzureClient.clusterStatus = azure.OperationStatus{
+		InProgress:  true,
+		Type:        "Upgrading",
+		OperationID: "op-1",
+	}
+	r := newPreflightReconciler(t, azureClient)
+
+	require.NoError(t, r.syncOperations(context.Background()))
+
+	r.recordPreflightCondition(context.Background(), metav1.ConditionFalse, "credential expired")
+
+	active, err := r.findActiveCR(context.Background(), map[string]string{
+		"azure.cluster.name":   "test-cluster",
+		"azure.resource.group": "test-rg",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, active)
+
+	cond := meta.FindStatusCondition(active.Status.Conditions, apiv1.ConditionPreflightSucceeded)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults for Rule.MinDuration/Rule.ConsecutiveSamples when a rule leaves
+// them unset, chosen so a single metrics spike during a rollout doesn't
+// immediately abort an operation.
+const (
+	DefaultMinDuration        = 60 * time.Second
+	DefaultConsecutiveSamples = 3
+)
+
+// ruleState is one rule's hysteresis window for one operation: how many
+// metricsUpdated signals in a row it's matched, and when the first of those
+// matches happened.
+type ruleState struct {
+	consecutiveMatches int
+	firstBreach        time.Time
+	targetSamples      int
+	targetDuration     time.Duration
+}
+
+// RuleEvaluator owns the hysteresis state for every rule evaluated against
+// one Operation, across repeated metricsUpdated signals. One is kept per
+// Operation until operation-monitor sees that Operation CR deleted, since
+// deciding whether a breach is sustained enough to abort on needs the
+// history of past evaluations, not just the latest one.
+type RuleEvaluator struct {
+	mu     sync.Mutex
+	states map[string]*ruleState // keyed by rule name
+}
+
+// NewRuleEvaluator creates an evaluator with no prior history.
+func NewRuleEvaluator() *RuleEvaluator {
+	return &RuleEvaluator{
+		states: make(map[string]*ruleState),
+	}
+}
+
+// Observe updates every rule's hysteresis window against this round's
+// violations and returns the subset that's now sustained - continuously
+// matched for at least ConsecutiveSamples evaluations or MinDuration,
+// whichever comes first. A rule that didn't match this round has its window
+// reset, so recovery within the window clears the breach entirely rather
+// than merely pausing it.
+func (e *RuleEvaluator) Observe(ruleSet *RuleSet, violations []ThresholdViolation) []ThresholdViolation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	matched := make(map[string]ThresholdViolation, len(violations))
+	for _, v := range violations {
+		matched[v.RuleName] = v
+	}
+
+	var sustained []ThresholdViolation
+	now := time.Now()
+
+	for _, rule := range ruleSet.rules {
+		v, isMatch := matched[rule.Name]
+		if !isMatch {
+			delete(e.states, rule.Name)
+			continue
+		}
+
+		st, exists := e.states[rule.Name]
+		if !exists {
+			minDuration := rule.MinDuration
+			if minDuration <= 0 {
+				minDuration = DefaultMinDuration
+			}
+			consecutiveSamples := rule.ConsecutiveSamples
+			if consecutiveSamples <= 0 {
+				consecutiveSamples = DefaultConsecutiveSamples
+			}
+			st = &ruleState{firstBreach: now, targetSamples: consecutiveSamples, targetDuration: minDuration}
+			e.states[rule.Name] = st
+		}
+		st.consecutiveMatches++
+
+		if st.consecutiveMatches >= st.targetSamples || now.Sub(st.firstBreach) >= st.targetDuration {
+			sustained = append(sustained, v)
+		}
+	}
+
+	return sustained
+}
+
+// Status summarizes every rule currently mid-window, e.g. "observing: 2/3
+// samples for crash_loop_percent", for the Operation CR status to surface
+// while a breach hasn't sustained long enough to act on yet.
+func (e *RuleEvaluator) Status() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	lines := make([]string, 0, len(e.states))
+	for name, st := range e.states {
+		lines = append(lines, fmt.Sprintf("observing: %d/%d samples for %s", st.consecutiveMatches, st.targetSamples, name))
+	}
+	return lines
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// Severity values a Rule's "severity" field may take - anything else fails
+// NewRuleSet. SeverityAbort rules request an abort once RuleEvaluator
+// finds their match sustained; SeverityWarn rules never do, and are only
+// reported (Events, metrics, the Operation CR's status).
+const (
+	SeverityWarn  = "warn"
+	SeverityAbort = "abort"
+)
+
+// ThresholdViolation is one Rule that matched against a metrics payload.
+type ThresholdViolation struct {
+	RuleName string
+	Severity string
+	Message  string
+
+	// Reason is passed to abort(...) verbatim for SeverityAbort violations;
+	// it's the Rule's AbortReason, or RuleName if that was left blank.
+	Reason string
+
+	// AgentPool is the node pool this violation is attributed to, set from
+	// the Rule's AgentPoolExpression when present. Left empty for a
+	// cluster-wide violation, which aborts the whole operation instead of
+	// one pool.
+	AgentPool string
+}
+
+// Rule is one entry in the metric-thresholds ConfigMap's rules.json: a
+// named CEL boolean expression evaluated against the full
+// current_metrics.json payload (bound as the "parsed" variable), plus what
+// to do when it matches. MinDuration/ConsecutiveSamples feed
+// RuleEvaluator's hysteresis - see its doc comment - and fall back to
+// DefaultMinDuration/DefaultConsecutiveSamples when left unset.
+type Rule struct {
+	Name        string
+	Expression  string
+	Severity    string
+	AbortReason string
+
+	MinDuration        time.Duration
+	ConsecutiveSamples int
+
+	// AgentPoolExpression is an optional second CEL expression, evaluated
+	// only when Expression matches, that must return the string name of the
+	// node pool the breach is attributable to (e.g.
+	// "string(parsed.node_metrics.pool)" - a bare field of parsed types as
+	// dyn, which NewRuleSet rejects). Rules that don't concentrate on a single
+	// pool leave this blank, and their violations stay cluster-scoped.
+	AgentPoolExpression string
+}
+
+// rawRule is the on-the-wire shape of one rules.json entry. MinDuration is
+// a duration string (e.g. "60s") here since encoding/json has no native
+// time.Duration support; NewRuleSet parses it into Rule.MinDuration.
+type rawRule struct {
+	Name                string `json:"name"`
+	Expression          string `json:"expression"`
+	Severity            string `json:"severity"`
+	AbortReason         string `json:"abortReason"`
+	MinDuration         string `json:"minDuration"`
+	ConsecutiveSamples  int    `json:"consecutiveSamples"`
+	AgentPoolExpression string `json:"agentPoolExpression"`
+}
+
+// rulesDoc is the shape of rules.json in the metric-thresholds ConfigMap.
+type rulesDoc struct {
+	Rules []rawRule `json:"rules"`
+}
+
+// compiledRule pairs a Rule with its compiled CEL program(s), so Evaluate
+// runs them without reparsing or type-checking the expression(s). The
+// programs live only as long as their RuleSet: operation-monitor builds a
+// new RuleSet from rules.json, compiling every rule again, on each check.
+// agentPoolProgram is nil when the Rule has no AgentPoolExpression.
+type compiledRule struct {
+	Rule
+	program          cel.Program
+	agentPoolProgram cel.Program
+}
+
+// RuleSet is a compiled collection of Rules, evaluated together against one
+// metrics payload by Evaluate. It replaces the old fixed catalog of
+// checkNodeMetrics/checkPodMetrics/etc. checks: any expression over the
+// metrics JSON is now a rule, not a Go function.
+type RuleSet struct {
+	rules []compiledRule
+}
+
+// NewRuleSet parses rules.json and compiles every rule's expression against
+// a CEL environment exposing "parsed" (the full metrics JSON, as
+// map[string]dyn). It fails closed - a ConfigMap with one bad rule fails the
+// whole load rather than silently dropping a check the cluster operator is
+// relying on.
+func NewRuleSet(raw []byte) (*RuleSet, error) {
+	var doc rulesDoc
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse rules.json: %w", err)
+	}
+
+	env, err := cel.NewEnv(cel.Variable("parsed", cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	rs := &RuleSet{}
+	for _, rr := range doc.Rules {
+		if rr.Name == "" {
+			return nil, fmt.Errorf("rule missing name")
+		}
+		switch rr.Severity {
+		case SeverityWarn, SeverityAbort:
+		default:
+			return nil, fmt.Errorf("rule %q: severity must be %q or %q, got %q", rr.Name, SeverityWarn, SeverityAbort, rr.Severity)
+		}
+
+		minDuration := time.Duration(0)
+		if rr.MinDuration != "" {
+			d, err := time.ParseDuration(rr.MinDuration)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid minDuration %q: %w", rr.Name, rr.MinDuration, err)
+			}
+			minDuration = d
+		}
+
+		rule := Rule{
+			Name:                rr.Name,
+			Expression:          rr.Expression,
+			Severity:            rr.Severity,
+			AbortReason:         rr.AbortReason,
+			MinDuration:         minDuration,
+			ConsecutiveSamples:  rr.ConsecutiveSamples,
+			AgentPoolExpression: rr.AgentPoolExpression,
+		}
+
+		ast, iss := env.Compile(rule.Expression)
+		if iss != nil && iss.Err() != nil {
+			return nil, fmt.Errorf("rule %q: failed to compile expression %q: %w", rule.Name, rule.Expression, iss.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("rule %q: expression %q must evaluate to a bool, got %s", rule.Name, rule.Expression, ast.OutputType())
+		}
+
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: failed to build program: %w", rule.Name, err)
+		}
+
+		var agentPoolProgram cel.Program
+		if rule.AgentPoolExpression != "" {
+			poolAst, iss := env.Compile(rule.AgentPoolExpression)
+			if iss != nil && iss.Err() != nil {
+				return nil, fmt.Errorf("rule %q: failed to compile agentPoolExpression %q: %w", rule.Name, rule.AgentPoolExpression, iss.Err())
+			}
+			if poolAst.OutputType() != cel.StringType {
+				return nil, fmt.Errorf("rule %q: agentPoolExpression %q must evaluate to a string, got %s", rule.Name, rule.AgentPoolExpression, poolAst.OutputType())
+			}
+			agentPoolProgram, err = env.Program(poolAst)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: failed to build agentPoolExpression program: %w", rule.Name, err)
+			}
+		}
+
+		rs.rules = append(rs.rules, compiledRule{Rule: rule, program: program, agentPoolProgram: agentPoolProgram})
+	}
+
+	return rs, nil
+}
+
+// Evaluate runs every rule against parsed (the unmarshalled
+// current_metrics.json) and returns a ThresholdViolation for each rule that
+// matched. A rule whose expression errors at evaluation time - e.g. it
+// indexes a field parsed doesn't have this round - is logged and skipped
+// rather than failing the whole batch, since one badly-written rule
+// shouldn't block every other rule from running.
+func (rs *RuleSet) Evaluate(parsed map[string]interface{}) []ThresholdViolation {
+	var violations []ThresholdViolation
+
+	for _, rule := range rs.rules {
+		out, _, err := rule.program.Eval(map[string]interface{}{"parsed": parsed})
+		if err != nil {
+			fmt.Printf("Rule %q failed to evaluate: %v\n", rule.Name, err)
+			continue
+		}
+
+		matched, ok := out.Value().(bool)
+		if !ok || !matched {
+			continue
+		}
+
+		reason := rule.AbortReason
+		if reason == "" {
+			reason = rule.Name
+		}
+
+		v := ThresholdViolation{
+			RuleName: rule.Name,
+			Severity: rule.Severity,
+			Message:  fmt.Sprintf("rule %q matched: %s", rule.Name, rule.Expression),
+			Reason:   reason,
+		}
+
+		if rule.agentPoolProgram != nil {
+			poolOut, _, err := rule.agentPoolProgram.Eval(map[string]interface{}{"parsed": parsed})
+			if err != nil {
+				fmt.Printf("Rule %q failed to evaluate agentPoolExpression: %v\n", rule.Name, err)
+			} else if pool, ok := poolOut.Value().(string); ok {
+				v.AgentPool = pool
+			}
+		}
+
+		violations = append(violations, v)
+	}
+
+	return violations
+}
@@ -1,58 +1,60 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"os"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+
+	"kops/config"
 	"kops/controllers"
 	"kops/internal/azure"
 )
 
 func main() {
+	controllersFlag := flag.String("controllers", "", `comma-separated list of controllers to disable, e.g. "-abort-executor"`)
+	metricsAddr := flag.String("metrics-addr", "", "bind address for /metrics, /healthz, and /readyz (defaults to controllers.DefaultMetricsServerAddr)")
+	flag.Parse()
 
-	// Load Azure configuration
-	SubscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID")
-	ResourceGroupName := os.Getenv("AZURE_RESOURCE_GROUP")
-	ClusterName := os.Getenv("AZURE_CLUSTER_NAME")
-
-	// Authenticate using default Azure credentials
-	cred, err := azure.GetDefaultAzureCredential()
+	// Authenticate to the target AKS cluster using the same credential
+	// chain and AZURE_SUBSCRIPTION_ID/AZURE_RESOURCE_GROUP/AZURE_CLUSTER_NAME
+	// env vars as the main operator binary.
+	azureCfg, err := config.LoadAzureConfig()
 	if err != nil {
-		fmt.Printf("Error initializing Azure credentials: %v\n", err)
+		fmt.Printf("Error loading Azure config: %v\n", err)
 		return
 	}
-	
-	// Create AKS client
-	aksClient, err := azure.GetAKSClient(SubscriptionID, ResourceGroupName, ClusterName, cred)
+	azureClient, err := azure.NewClient(context.Background(), azureCfg)
 	if err != nil {
-		fmt.Printf("Error creating AKS client: %v\n", err)
+		fmt.Printf("Error creating Azure client: %v\n", err)
 		return
 	}
-	
-	// Fetch kubeconfig from Monitoring AKS cluster
+
+	// This binary runs inside the management cluster it watches Operation
+	// CRs and the metrics-store/metric-thresholds ConfigMaps on, so typed,
+	// dynamic, and base clients all come from the same in-cluster config.
 	restCfg, err := rest.InClusterConfig()
 	if err != nil {
 		fmt.Printf("Error getting in-cluster config: %v\n", err)
 		return
 	}
-	
-	// Create typed Kubernetes client (used for ConfigMap and accessing user-defined thresholds)
-	typedClient, err := azure.GetTypedClient(restCfg)
+
+	typedClient, err := kubernetes.NewForConfig(restCfg)
 	if err != nil {
 		fmt.Printf("Error creating typed Kubernetes client: %v\n", err)
 		return
 	}
 
-	// Create dynamic Kubernetes client (used for custom resources)
-	dynClient, err := azure.GetDynamicClient(restCfg)
+	dynClient, err := dynamic.NewForConfig(restCfg)
 	if err != nil {
 		fmt.Printf("Error creating dynamic Kubernetes client: %v\n", err)
 		return
 	}
 
-	// Start health monitoring
-	err = controllers.StartHealthMonitoring(aksClient, typedClient, dynClient)
-	if err != nil {
+	if err := controllers.StartHealthMonitoring(azureClient, typedClient, dynClient, typedClient, *controllersFlag, *metricsAddr); err != nil {
 		fmt.Printf("Failed to start health monitoring: %v\n", err)
 		return
 	}
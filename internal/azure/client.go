@@ -3,13 +3,11 @@ package azure
 import (
 	"context"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
 	"kops/config"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v4"
 	"k8s.io/klog/v2"
 )
@@ -23,41 +21,47 @@ type OperationStatus struct {
 	StartTime   *time.Time
 	EndTime     *time.Time
 	Error       string
+
+	// AgentPool is the node pool a NodePoolScale/NodePoolUpgrade operation
+	// is scoped to, left empty for cluster-wide operation types.
+	AgentPool string
+	// AgentPoolStatus is AgentPool's own provisioning state, fetched via
+	// GetAgentPoolStatus when AgentPool is set - nil otherwise, or if that
+	// fetch failed.
+	AgentPoolStatus *AgentPoolOperationStatus
+}
+
+// AgentPoolOperationStatus represents the status of an operation on a
+// single agent pool, since pools upgrade/scale independently of the
+// cluster and of each other.
+type AgentPoolOperationStatus struct {
+	AgentPoolName string
+	InProgress    bool
+	Type          string
+	Status        string
+	PowerState    string
+	OperationID   string
 }
 
 // Client wraps the Azure Container Service client
 type Client struct {
 	azureClient       *armcontainerservice.ManagedClustersClient // ← Renamed from aksClient
+	agentPoolsClient  *armcontainerservice.AgentPoolsClient
 	subscriptionID    string
 	resourceGroupName string
 	clusterName       string
 }
 
-// for easy test, use DefaultAzureCredential
-//func NewClient(azureConfig config.AzureConfig) (*Client, error) {
-//klog.InfoS("Creating Azure client")
-
-//cred, err := azidentity.NewDefaultAzureCredential(nil)
-//if err != nil {
-//	return nil, fmt.Errorf("failed to obtain Azure credential: %w", err)
-//	}
-
-func NewClient(azureConfig config.AzureConfig) (*Client, error) {
-	klog.InfoS("Creating Azure client using Managed Identity")
+// NewClient builds a Client authenticated via azureConfig.NewCredential,
+// so the credential chain (managed identity, workload identity, a client
+// secret, the Azure CLI, ...) is selected by azureConfig.CredentialMode
+// rather than hardcoded here.
+func NewClient(ctx context.Context, azureConfig config.AzureConfig) (*Client, error) {
+	klog.InfoS("Creating Azure client", "credentialMode", azureConfig.CredentialMode)
 
-	clientID := os.Getenv("AZURE_CLIENT_ID")
-	opts := &azidentity.ManagedIdentityCredentialOptions{}
-
-	if clientID != "" {
-		klog.InfoS("Using User Assigned Managed Identity", "clientID", clientID)
-		opts.ID = azidentity.ClientID(clientID)
-	} else {
-		klog.InfoS("Using System Assigned Managed Identity")
-	}
-
-	cred, err := azidentity.NewManagedIdentityCredential(opts)
+	cred, err := azureConfig.NewCredential(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Managed Identity credential: %w", err)
+		return nil, fmt.Errorf("failed to obtain Azure credential: %w", err)
 	}
 
 	azureClient, err := armcontainerservice.NewManagedClustersClient(azureConfig.SubscriptionID, cred, nil)
@@ -65,8 +69,14 @@ func NewClient(azureConfig config.AzureConfig) (*Client, error) {
 		return nil, fmt.Errorf("failed to create Azure client: %w", err)
 	}
 
+	agentPoolsClient, err := armcontainerservice.NewAgentPoolsClient(azureConfig.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure agent pools client: %w", err)
+	}
+
 	client := &Client{
 		azureClient:       azureClient,
+		agentPoolsClient:  agentPoolsClient,
 		subscriptionID:    azureConfig.SubscriptionID,
 		resourceGroupName: azureConfig.ResourceGroupName,
 		clusterName:       azureConfig.ClusterName,
@@ -113,21 +123,155 @@ func (c *Client) GetClusterOperationStatus(ctx context.Context) (OperationStatus
 		status.OperationID = fmt.Sprintf("%s-%s-%d", c.clusterName, provisioningState, time.Now().Unix())
 
 		// Determine operation type based on cluster state
-		status.Type = c.determineOperationType(cluster)
+		status.Type, status.AgentPool = c.determineOperationType(cluster)
 
 		// Determine if operation is in progress
 		status.InProgress = c.isOperationInProgress(provisioningState)
 
+		if status.AgentPool != "" {
+			if poolStatus, err := c.GetAgentPoolStatus(ctx, status.AgentPool); err != nil {
+				klog.ErrorS(err, "Failed to get agent pool status", "cluster", c.clusterName, "agentPool", status.AgentPool)
+			} else {
+				status.AgentPoolStatus = &poolStatus
+			}
+		}
+
 		klog.V(2).InfoS("Cluster operation status",
 			"cluster", c.clusterName,
 			"status", status.Status,
 			"type", status.Type,
+			"agentPool", status.AgentPool,
 			"inProgress", status.InProgress)
 	}
 
 	return status, nil
 }
 
+// ListAgentPools returns the name of every agent pool on the cluster.
+func (c *Client) ListAgentPools(ctx context.Context) ([]string, error) {
+	var names []string
+
+	pager := c.agentPoolsClient.NewListPager(c.resourceGroupName, c.clusterName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list agent pools: %w", err)
+		}
+		for _, pool := range page.Value {
+			if pool != nil && pool.Name != nil {
+				names = append(names, *pool.Name)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// GetAgentPoolStatus reports a single named agent pool's provisioning state
+// and power state. Unlike GetAgentPoolOperationStatuses, which pages through
+// every pool on the cluster, this is for a caller that already knows which
+// pool it cares about - e.g. GetClusterOperationStatus, enriching a
+// NodePoolScale/NodePoolUpgrade status with that one pool's detail.
+func (c *Client) GetAgentPoolStatus(ctx context.Context, poolName string) (AgentPoolOperationStatus, error) {
+	pool, err := c.agentPoolsClient.Get(ctx, c.resourceGroupName, c.clusterName, poolName, nil)
+	if err != nil {
+		return AgentPoolOperationStatus{}, fmt.Errorf("failed to get agent pool %s: %w", poolName, err)
+	}
+
+	status := AgentPoolOperationStatus{
+		AgentPoolName: poolName,
+		Type:          "NodePoolOperation",
+		Status:        "Unknown",
+		OperationID:   fmt.Sprintf("%s-%s-unknown", c.clusterName, poolName),
+	}
+
+	if pool.Properties != nil {
+		if pool.Properties.ProvisioningState != nil {
+			status.Status = *pool.Properties.ProvisioningState
+			status.InProgress = c.isOperationInProgress(status.Status)
+			status.OperationID = fmt.Sprintf("%s-%s-%s-%d", c.clusterName, poolName, status.Status, time.Now().Unix())
+		}
+		if pool.Properties.PowerState != nil && pool.Properties.PowerState.Code != nil {
+			status.PowerState = string(*pool.Properties.PowerState.Code)
+		}
+	}
+
+	return status, nil
+}
+
+// GetAgentPoolOperationStatuses enumerates every agent pool on the cluster
+// and reports its own ProvisioningState/PowerState, since simultaneous
+// per-pool upgrades or scales are otherwise invisible in the cluster-level
+// ProvisioningState returned by GetClusterOperationStatus.
+func (c *Client) GetAgentPoolOperationStatuses(ctx context.Context) ([]AgentPoolOperationStatus, error) {
+	var statuses []AgentPoolOperationStatus
+
+	pager := c.agentPoolsClient.NewListPager(c.resourceGroupName, c.clusterName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list agent pools: %w", err)
+		}
+
+		for _, pool := range page.Value {
+			if pool == nil || pool.Name == nil {
+				continue
+			}
+
+			status := AgentPoolOperationStatus{
+				AgentPoolName: *pool.Name,
+				Type:          "NodePoolOperation",
+				Status:        "Unknown",
+				OperationID:   fmt.Sprintf("%s-%s-unknown", c.clusterName, *pool.Name),
+			}
+
+			if pool.Properties != nil {
+				if pool.Properties.ProvisioningState != nil {
+					status.Status = *pool.Properties.ProvisioningState
+					status.InProgress = c.isOperationInProgress(status.Status)
+					status.OperationID = fmt.Sprintf("%s-%s-%s-%d", c.clusterName, *pool.Name, status.Status, time.Now().Unix())
+				}
+				if pool.Properties.PowerState != nil && pool.Properties.PowerState.Code != nil {
+					status.PowerState = string(*pool.Properties.PowerState.Code)
+				}
+			}
+
+			klog.V(2).InfoS("Agent pool operation status",
+				"cluster", c.clusterName,
+				"agentPool", status.AgentPoolName,
+				"status", status.Status,
+				"powerState", status.PowerState,
+				"inProgress", status.InProgress)
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+// AbortAgentPoolOperation attempts to abort the ongoing operation on a
+// single agent pool, mirroring AbortClusterOperation.
+func (c *Client) AbortAgentPoolOperation(ctx context.Context, agentPoolName, reason string) error {
+	klog.InfoS("Attempting to abort agent pool operation", "cluster", c.clusterName, "agentPool", agentPoolName, "reason", reason)
+
+	poller, err := c.agentPoolsClient.BeginAbortLatestOperation(ctx, c.resourceGroupName, c.clusterName, agentPoolName, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "409") || strings.Contains(err.Error(), "Conflict") {
+			return fmt.Errorf("operation completed before abort could take effect: %w", err)
+		}
+		return fmt.Errorf("failed to initiate agent pool abort operation: %w", err)
+	}
+
+	_, err = poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("agent pool abort operation failed: %w", err)
+	}
+
+	klog.InfoS("Agent pool operation aborted successfully", "cluster", c.clusterName, "agentPool", agentPoolName)
+	return nil
+}
+
 // GetAdminKubeconfig retrieves the admin kubeconfig for the target cluster
 func (c *Client) GetAdminKubeconfig(ctx context.Context, clusterName, resourceGroup string) (string, error) {
 	klog.V(2).InfoS("Getting admin kubeconfig", "cluster", clusterName)
@@ -202,29 +346,41 @@ func (c *Client) GetClusterInfo(ctx context.Context) (map[string]interface{}, er
 	return info, nil
 }
 
-// Helper methods remain the same...
-func (c *Client) determineOperationType(cluster armcontainerservice.ManagedClustersClientGetResponse) string {
+// determineOperationType classifies the in-flight cluster operation and,
+// for a NodePoolScale/NodePoolUpgrade, reports which pool it's scoped to -
+// so a caller can abort just that pool instead of the whole cluster.
+func (c *Client) determineOperationType(cluster armcontainerservice.ManagedClustersClientGetResponse) (string, string) {
 	if cluster.Properties == nil {
-		return "ClusterOperation"
+		return "ClusterOperation", ""
 	}
 
 	if cluster.Properties.KubernetesVersion != nil {
-		return "ClusterUpgrade"
+		return "ClusterUpgrade", ""
 	}
 
 	if cluster.Properties.AgentPoolProfiles != nil {
 		for _, pool := range cluster.Properties.AgentPoolProfiles {
-			if pool.ProvisioningState != nil && c.isOperationInProgress(string(*pool.ProvisioningState)) {
-				return "NodePoolScale"
+			if pool.ProvisioningState == nil || !c.isOperationInProgress(string(*pool.ProvisioningState)) {
+				continue
+			}
+
+			poolName := ""
+			if pool.Name != nil {
+				poolName = *pool.Name
+			}
+
+			if strings.EqualFold(*pool.ProvisioningState, "Upgrading") {
+				return "NodePoolUpgrade", poolName
 			}
+			return "NodePoolScale", poolName
 		}
 	}
 
 	if cluster.Properties.AddonProfiles != nil {
-		return "AddonUpdate"
+		return "AddonUpdate", ""
 	}
 
-	return "ClusterUpdate"
+	return "ClusterUpdate", ""
 }
 
 func (c *Client) isOperationInProgress(provisioningState string) bool {
@@ -244,7 +400,9 @@ func (c *Client) isOperationInProgress(provisioningState string) bool {
 // AzureClientInterface defines the interface for Azure operations
 type AzureClientInterface interface {
 	GetClusterOperationStatus(ctx context.Context) (OperationStatus, error)
+	GetAgentPoolOperationStatuses(ctx context.Context) ([]AgentPoolOperationStatus, error)
 	GetAdminKubeconfig(ctx context.Context, clusterName, resourceGroup string) (string, error)
 	TestConnection(ctx context.Context) error
 	AbortClusterOperation(ctx context.Context, reason string) error
+	AbortAgentPoolOperation(ctx context.Context, agentPoolName, reason string) error
 }
@@ -0,0 +1,378 @@
+// Package clustercache manages live connections to many AKS clusters at
+// once. client.GetKubeClientForAKSCluster builds a one-shot rest.Config and
+// Clientset for a single cluster; Cache wraps that same fetch in a
+// registry keyed by (subscription, resourceGroup, clusterName), caches the
+// result, health-probes it in the background, and reconnects with backoff
+// when a probe fails - so a controller watching dozens of clusters doesn't
+// have to reimplement connection pooling and retry logic itself.
+package clustercache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterKey identifies one AKS cluster a Cache manages a connection for.
+type ClusterKey struct {
+	SubscriptionID string
+	ResourceGroup  string
+	ClusterName    string
+}
+
+func (k ClusterKey) String() string {
+	return fmt.Sprintf("%s/%s/%s", k.SubscriptionID, k.ResourceGroup, k.ClusterName)
+}
+
+// FetchFunc obtains a fresh rest.Config/Clientset (and the Azure credential
+// used to build it) for key. client.GetKubeConfigAndClientForAKSCluster has
+// this shape; tests substitute a fake that can fail on demand.
+type FetchFunc func(ctx context.Context, key ClusterKey) (*rest.Config, *kubernetes.Clientset, azcore.TokenCredential, error)
+
+// EventType describes a connectivity transition an entry went through.
+type EventType string
+
+const (
+	EventConnected    EventType = "Connected"
+	EventDisconnected EventType = "Disconnected"
+)
+
+// Event is delivered to every Handler registered via Watch.
+type Event struct {
+	Key  ClusterKey
+	Type EventType
+	Err  error
+}
+
+// Handler receives connect/disconnect events as they happen. It's called
+// synchronously from the probe loop or from GetClient, so it should return
+// quickly.
+type Handler func(Event)
+
+const (
+	// DefaultProbeInterval is how often every registered cluster is
+	// health-checked in the background.
+	DefaultProbeInterval = 30 * time.Second
+	DefaultProbeTimeout  = 10 * time.Second
+	// DefaultMaxBackoff caps the reconnect delay after repeated failures.
+	DefaultMaxBackoff = 5 * time.Minute
+
+	minBackoff = 5 * time.Second
+)
+
+// Config tunes the background health probe loop.
+type Config struct {
+	ProbeInterval time.Duration
+	ProbeTimeout  time.Duration
+	MaxBackoff    time.Duration
+}
+
+type entry struct {
+	key ClusterKey
+
+	mu         sync.Mutex
+	restConfig *rest.Config
+	clientset  *kubernetes.Clientset
+	credential azcore.TokenCredential
+	healthy    bool
+	lastError  error
+	failures   int
+	nextProbe  time.Time
+}
+
+// Cache lazily builds and caches a Kubernetes connection per registered AKS
+// cluster, health-probes each one in the background, and reconnects with
+// jittered exponential backoff when a probe fails.
+type Cache struct {
+	fetch  FetchFunc
+	config Config
+
+	mu       sync.Mutex
+	entries  map[ClusterKey]*entry
+	handlers []Handler
+
+	stopCh  chan struct{}
+	running bool
+}
+
+// New creates a Cache. fetch is usually client.GetKubeConfigAndClientForAKSCluster.
+func New(fetch FetchFunc, config Config) (*Cache, error) {
+	if fetch == nil {
+		return nil, fmt.Errorf("fetch func cannot be nil")
+	}
+	if config.ProbeInterval == 0 {
+		config.ProbeInterval = DefaultProbeInterval
+	}
+	if config.ProbeTimeout == 0 {
+		config.ProbeTimeout = DefaultProbeTimeout
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+
+	return &Cache{
+		fetch:   fetch,
+		config:  config,
+		entries: make(map[ClusterKey]*entry),
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// Register adds key to the cache if it isn't already tracked. The
+// connection itself is built lazily, by GetClient or the background probe
+// loop, not here.
+func (c *Cache) Register(key ClusterKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = &entry{key: key}
+}
+
+// Unregister stops tracking key; a later Register starts it fresh.
+func (c *Cache) Unregister(key ClusterKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Keys returns every cluster currently registered, regardless of health.
+func (c *Cache) Keys() []ClusterKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]ClusterKey, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Healthy reports whether key's most recent connection attempt (lazy
+// connect or background probe) succeeded, and the error from it otherwise.
+func (c *Cache) Healthy(key ClusterKey) (healthy bool, lastError error, found bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return false, nil, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.healthy, e.lastError, true
+}
+
+// Watch registers handler to receive every future connect/disconnect Event.
+// It does not replay history that happened before Watch was called.
+func (c *Cache) Watch(handler Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+func (c *Cache) notify(event Event) {
+	c.mu.Lock()
+	handlers := make([]Handler, len(c.handlers))
+	copy(handlers, c.handlers)
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// GetClient returns the cached Clientset for key, registering and/or
+// (re)connecting it first if needed.
+func (c *Cache) GetClient(ctx context.Context, key ClusterKey) (*kubernetes.Clientset, error) {
+	e, err := c.entryFor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.clientset, nil
+}
+
+// GetRestConfig returns the cached rest.Config for key, registering and/or
+// (re)connecting it first if needed. Callers that need their own typed
+// clientset (e.g. the versioned metrics API) build it from this.
+func (c *Cache) GetRestConfig(ctx context.Context, key ClusterKey) (*rest.Config, error) {
+	e, err := c.entryFor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.restConfig, nil
+}
+
+// GetCredential returns the Azure credential used to build key's current
+// connection, registering and/or (re)connecting it first if needed.
+func (c *Cache) GetCredential(ctx context.Context, key ClusterKey) (azcore.TokenCredential, error) {
+	e, err := c.entryFor(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.credential, nil
+}
+
+func (c *Cache) entryFor(ctx context.Context, key ClusterKey) (*entry, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &entry{key: key}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.healthy && e.clientset != nil {
+		return e, nil
+	}
+
+	if err := c.connect(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// connect calls fetch and updates e in place. Caller must hold e.mu.
+func (c *Cache) connect(ctx context.Context, e *entry) error {
+	restConfig, clientset, cred, err := c.fetch(ctx, e.key)
+	if err != nil {
+		e.healthy = false
+		e.lastError = err
+		e.failures++
+		e.nextProbe = time.Now().Add(backoff(e.failures, c.config.MaxBackoff))
+		c.notify(Event{Key: e.key, Type: EventDisconnected, Err: err})
+		return fmt.Errorf("failed to connect to cluster %s: %w", e.key, err)
+	}
+
+	wasHealthy := e.healthy
+	e.restConfig = restConfig
+	e.clientset = clientset
+	e.credential = cred
+	e.healthy = true
+	e.lastError = nil
+	e.failures = 0
+	e.nextProbe = time.Time{}
+
+	if !wasHealthy {
+		c.notify(Event{Key: e.key, Type: EventConnected})
+	}
+	return nil
+}
+
+// backoff returns the delay before the (failures+1)-th connection attempt,
+// doubling per consecutive failure up to max and jittered +/-20% the way
+// OperationReconciler's poll jitter is, so many clusters failing at once
+// don't all retry in lockstep.
+func backoff(failures int, max time.Duration) time.Duration {
+	d := minBackoff
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	delta := time.Duration(float64(d) * 0.2)
+	if delta <= 0 {
+		return d
+	}
+	return d - delta + time.Duration(rand.Int63n(int64(2*delta)))
+}
+
+// Start launches the background probe loop that health-checks every
+// registered cluster every ProbeInterval and reconnects unhealthy ones once
+// their backoff has elapsed.
+func (c *Cache) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return fmt.Errorf("already running")
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	go c.probeLoop(ctx)
+	return nil
+}
+
+// Stop halts the background probe loop. It does not close any cached
+// connections.
+func (c *Cache) Stop() {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return
+	}
+	c.running = false
+	c.mu.Unlock()
+
+	close(c.stopCh)
+}
+
+func (c *Cache) probeLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.config.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.probeAll(ctx)
+		case <-c.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cache) probeAll(ctx context.Context) {
+	c.mu.Lock()
+	entries := make([]*entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+
+	for _, e := range entries {
+		c.probeOne(ctx, e)
+	}
+}
+
+// probeOne checks a single healthy entry's connection and marks it
+// unhealthy on failure, or (re)connects an unhealthy one whose backoff has
+// elapsed.
+func (c *Cache) probeOne(ctx context.Context, e *entry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.healthy && e.clientset != nil {
+		_, err := e.clientset.Discovery().ServerVersion()
+		if err == nil {
+			return
+		}
+
+		e.healthy = false
+		e.lastError = err
+		e.failures++
+		e.nextProbe = time.Now().Add(backoff(e.failures, c.config.MaxBackoff))
+		c.notify(Event{Key: e.key, Type: EventDisconnected, Err: err})
+		return
+	}
+
+	if !e.nextProbe.IsZero() && time.Now().Before(e.nextProbe) {
+		return
+	}
+
+	// connect logs its own failure via notify; an error here just means
+	// we'll try again once nextProbe elapses.
+	_ = c.connect(ctx, e)
+}
@@ -0,0 +1,130 @@
+package clustercache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeCredential satisfies azcore.TokenCredential without ever calling out
+// to Azure.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{}, nil
+}
+
+// fakeFetcher simulates client.GetKubeConfigAndClientForAKSCluster: it
+// fails the first failUntil calls for a given key, then succeeds.
+type fakeFetcher struct {
+	mu        sync.Mutex
+	calls     map[ClusterKey]int
+	failUntil int
+}
+
+func newFakeFetcher(failUntil int) *fakeFetcher {
+	return &fakeFetcher{calls: make(map[ClusterKey]int), failUntil: failUntil}
+}
+
+func (f *fakeFetcher) asFetchFunc() FetchFunc {
+	return func(_ context.Context, key ClusterKey) (*rest.Config, *kubernetes.Clientset, azcore.TokenCredential, error) {
+		f.mu.Lock()
+		f.calls[key]++
+		n := f.calls[key]
+		f.mu.Unlock()
+
+		if n <= f.failUntil {
+			return nil, nil, nil, fmt.Errorf("transient failure %d for %s", n, key)
+		}
+
+		clientset, err := kubernetes.NewForConfig(&rest.Config{Host: "https://example.invalid"})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return &rest.Config{}, clientset, fakeCredential{}, nil
+	}
+}
+
+func TestCache_GetClient_RetriesOnTransientFailure(t *testing.T) {
+	fetcher := newFakeFetcher(2)
+	cache, err := New(fetcher.asFetchFunc(), Config{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	key := ClusterKey{SubscriptionID: "sub", ResourceGroup: "rg", ClusterName: "cluster"}
+	cache.Register(key)
+
+	if _, err := cache.GetClient(context.Background(), key); err == nil {
+		t.Fatalf("expected failure on first attempt")
+	}
+	if _, err := cache.GetClient(context.Background(), key); err == nil {
+		t.Fatalf("expected failure on second attempt")
+	}
+	if _, err := cache.GetClient(context.Background(), key); err != nil {
+		t.Fatalf("expected success on third attempt, got %v", err)
+	}
+
+	healthy, lastErr, found := cache.Healthy(key)
+	if !found {
+		t.Fatalf("expected entry to be found")
+	}
+	if !healthy {
+		t.Fatalf("expected entry to be healthy after successful connect, lastErr=%v", lastErr)
+	}
+}
+
+func TestCache_GetClient_FiresConnectedEventOnce(t *testing.T) {
+	fetcher := newFakeFetcher(1)
+	cache, err := New(fetcher.asFetchFunc(), Config{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var events []Event
+	var mu sync.Mutex
+	cache.Watch(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+
+	key := ClusterKey{SubscriptionID: "sub", ResourceGroup: "rg", ClusterName: "cluster"}
+	cache.Register(key)
+
+	cache.GetClient(context.Background(), key)
+	cache.GetClient(context.Background(), key)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events (disconnected, connected), got %d: %+v", len(events), events)
+	}
+	if events[0].Type != EventDisconnected {
+		t.Errorf("expected first event to be Disconnected, got %s", events[0].Type)
+	}
+	if events[1].Type != EventConnected {
+		t.Errorf("expected second event to be Connected, got %s", events[1].Type)
+	}
+}
+
+func TestBackoff_GrowsAndCaps(t *testing.T) {
+	max := 1 * time.Minute
+
+	first := backoff(1, max)
+	if first < minBackoff/2 || first > minBackoff*2 {
+		t.Errorf("expected first backoff near minBackoff=%s, got %s", minBackoff, first)
+	}
+
+	capped := backoff(20, max)
+	if capped > max+time.Duration(float64(max)*0.2) {
+		t.Errorf("expected backoff capped near max=%s, got %s", max, capped)
+	}
+}
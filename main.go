@@ -6,145 +6,237 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"kops/client"
+	"kops/config"
+	"kops/controllers"
+	"kops/internal/azure"
+	"kops/internal/clustercache"
 	"kops/metric_collector"
+	"kops/metric_collector/exporter"
+	"kops/metric_collector/storage"
 
-	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+	apiv1 "kops/api/v1"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 )
 
 func main() {
-
-	// Start health endpoint in a goroutine (non-blocking)
-	go func() {
-		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprintln(w, "OK")
-		})
-
-		port := "8080"
-		log.Printf("Health endpoint running on :%s\n", port)
-		err := http.ListenAndServe(":"+port, nil)
-		if err != nil {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
-
 	subscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID")
-	resourceGroup := os.Getenv("AZURE_RESOURCE_GROUP")
-	clusterName := os.Getenv("AZURE_CLUSTER_NAME")
 
-	kubeClient, err := client.GetKubeClientForAKSCluster(context.Background(), subscriptionID, resourceGroup, clusterName)
+	clusterKeys, err := parseClusterKeys(subscriptionID, os.Getenv("AZURE_CLUSTERS"), os.Getenv("AZURE_RESOURCE_GROUP"), os.Getenv("AZURE_CLUSTER_NAME"))
+	if err != nil {
+		fmt.Printf("Error parsing target clusters: %v\n", err)
+		return
+	}
+
+	cache, err := clustercache.New(func(ctx context.Context, key clustercache.ClusterKey) (*rest.Config, *kubernetes.Clientset, azcore.TokenCredential, error) {
+		return client.GetKubeConfigAndClientForAKSCluster(ctx, key.SubscriptionID, key.ResourceGroup, key.ClusterName)
+	}, clustercache.Config{})
 	if err != nil {
-		fmt.Printf("Error creating clientset: %v\n", err)
+		fmt.Printf("Error creating cluster cache: %v\n", err)
+		return
+	}
+	for _, key := range clusterKeys {
+		cache.Register(key)
+	}
+	if err := cache.Start(context.Background()); err != nil {
+		fmt.Printf("Error starting cluster cache: %v\n", err)
 		return
 	}
 
-	// Create a metrics client to collect resource usage
-	metricsConfig, err := rest.InClusterConfig()
+	// The ConfigMap bookkeeping, healthz checks, and OperationReconciler
+	// below all key off a single "primary" cluster (the first one
+	// configured); MetricsCollectorRunnable is what actually iterates
+	// every cluster the cache knows about. Tracking Operations for more
+	// than one cluster out of this binary would mean either one
+	// OperationReconciler per cluster or switching to FleetReconciler's
+	// tag-based discovery, which is a bigger change than this entrypoint
+	// rework and is left for later.
+	primary := clusterKeys[0]
+	kubeClient, err := cache.GetClient(context.Background(), primary)
 	if err != nil {
-		fmt.Printf("Error creating in-cluster config: %v\n", err)
+		fmt.Printf("Error creating clientset for primary cluster %s: %v\n", primary, err)
 		return
 	}
-	metricsClient, err := metrics.NewForConfig(metricsConfig)
+	azureCred, err := cache.GetCredential(context.Background(), primary)
 	if err != nil {
-		fmt.Printf("Error creating metrics client: %v\n", err)
+		fmt.Printf("Error fetching credential for primary cluster %s: %v\n", primary, err)
 		return
 	}
 
-	// Config Map
+	storageBackend, err := storage.NewFromEnv(kubeClient)
+	if err != nil {
+		fmt.Printf("Error creating storage backend: %v\n", err)
+		return
+	}
 
-	// Check if ConfigMap already exists and we create only if it doesn't
-	configMapName := "metrics-store"
-	namespace := "default"
+	azureCfg := config.CredentialFieldsFromEnv()
+	azureCfg.SubscriptionID = primary.SubscriptionID
+	azureCfg.ResourceGroupName = primary.ResourceGroup
+	azureCfg.ClusterName = primary.ClusterName
 
-	existingConfigMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(
-		context.Background(),
-		configMapName,
-		metav1.GetOptions{},
-	)
+	azureClient, err := azure.NewClient(context.Background(), azureCfg)
+	if err != nil {
+		fmt.Printf("Error creating Azure client for primary cluster %s: %v\n", primary, err)
+		return
+	}
 
+	// mgr owns the lifecycle (Start/Stop, leader election, health probes)
+	// of both OperationReconciler and MetricsCollectorRunnable below. Its
+	// own metrics server is disabled - promExporter already serves /metrics
+	// in the repo's existing format, and running both would double-bind.
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 apiv1.Scheme,
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: healthProbeAddr(),
+		LeaderElection:         os.Getenv("KOPS_LEADER_ELECTION") != "false",
+		LeaderElectionID:       "kops-operator-leader",
+	})
 	if err != nil {
-		// ConfigMap doesn't exist so we have to create a new one
-		fmt.Printf("ConfigMap '%s' not found, creating new one...\n", configMapName)
-
-		configMap := &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      configMapName,
-				Namespace: namespace,
-			},
-			Data: map[string]string{},
-		}
+		fmt.Printf("Error creating manager: %v\n", err)
+		return
+	}
 
-		createdConfigMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Create(
-			context.Background(),
-			configMap,
-			metav1.CreateOptions{},
-		)
-		if err != nil {
-			fmt.Printf("Error creating ConfigMap: %v\n", err)
-			return
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		fmt.Printf("Error registering healthz check: %v\n", err)
+		return
+	}
+	if err := mgr.AddReadyzCheck("metrics-storage-backend", func(r *http.Request) error {
+		_, err := storageBackend.Latest(r.Context())
+		return err
+	}); err != nil {
+		fmt.Printf("Error registering readyz check: %v\n", err)
+		return
+	}
+	if err := mgr.AddReadyzCheck("azure-credential", func(r *http.Request) error {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		_, err := azureCred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+		return err
+	}); err != nil {
+		fmt.Printf("Error registering readyz check: %v\n", err)
+		return
+	}
+	if err := mgr.AddReadyzCheck("metrics-collection-fresh", func(_ *http.Request) error {
+		last := metric_collector.LastCollectionSuccess()
+		if last.IsZero() {
+			return fmt.Errorf("no successful metrics collection yet")
 		}
+		if stale := 3 * DefaultMetricsInterval; time.Since(last) > stale {
+			return fmt.Errorf("last successful collection was %s ago, exceeds %s", time.Since(last), stale)
+		}
+		return nil
+	}); err != nil {
+		fmt.Printf("Error registering readyz check: %v\n", err)
+		return
+	}
 
-		fmt.Printf("ConfigMap '%s' created successfully in namespace '%s'\n",
-			createdConfigMap.Name, createdConfigMap.Namespace)
-	} else {
-		// ConfigMap already exists so we use the existing one
-		fmt.Printf("Found existing ConfigMap '%s' in namespace '%s'\n",
-			existingConfigMap.Name, existingConfigMap.Namespace)
-
-		// Show some info about existing data. How many collections and when was the last update time
-		if existingConfigMap.Data != nil {
-			if totalCollections, exists := existingConfigMap.Data["total_collections"]; exists {
-				fmt.Printf("Existing ConfigMap has %s previous collections\n", totalCollections)
-			}
-			if lastUpdated, exists := existingConfigMap.Data["last_updated"]; exists {
-				fmt.Printf("Last updated: %s\n", lastUpdated)
-			}
+	// Prometheus exporter keeps running its own tiny server, independent of
+	// the manager, since it serves a format (/metrics text exposition) the
+	// manager's disabled metrics server would otherwise also have served.
+	promExporter := exporter.New()
+	go func() {
+		mux := http.NewServeMux()
+		promExporter.RegisterOn(mux)
+		addr := os.Getenv("PROMETHEUS_METRICS_ADDR")
+		if addr == "" {
+			addr = exporter.DefaultBindAddr
 		}
+		log.Printf("Prometheus metrics endpoint running on %s (/metrics)\n", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Fatalf("Failed to start metrics server: %v", err)
+		}
+	}()
+
+	opReconciler, err := controllers.NewOperationReconciler(mgr.GetClient(), azureClient, controllers.Config{
+		ResourceGroup: primary.ResourceGroup,
+		ClusterName:   primary.ClusterName,
+		APIReader:     mgr.GetAPIReader(),
+	})
+	if err != nil {
+		fmt.Printf("Error creating operation reconciler: %v\n", err)
+		return
+	}
+	if err := mgr.Add(opReconciler); err != nil {
+		fmt.Printf("Error registering operation reconciler: %v\n", err)
+		return
 	}
 
-	// Set up metrics collection every 30 seconds
-	fmt.Println("\nStarting continuous metrics collection (every 30 seconds)...")
-	fmt.Println("Press Ctrl+C to stop")
+	metricsRunnable := &controllers.MetricsCollectorRunnable{
+		Cache:          cache,
+		ClusterKeys:    clusterKeys,
+		StorageBackend: storageBackend,
+		Exporter:       promExporter,
+		Sink:           controllers.NewConfigMapMetricsSink(mgr.GetClient(), "default", "metrics-store"),
+		Interval:       DefaultMetricsInterval,
+	}
+	if err := mgr.Add(metricsRunnable); err != nil {
+		fmt.Printf("Error registering metrics collector: %v\n", err)
+		return
+	}
 
-	// Create a ticker that triggers every 30 seconds
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	fmt.Printf("Starting manager across %d cluster(s) (leader election: %v)\n", len(clusterKeys), os.Getenv("KOPS_LEADER_ELECTION") != "false")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		fmt.Printf("Error running manager: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	// Collect metrics immediately on startup
-	fmt.Println("\n=== Initial metrics collection ===")
-	err = metric_collector.CollectAndStoreMetrics(kubeClient, metricsClient)
-	if err != nil {
-		fmt.Printf("Error collecting initial metrics: %v\n", err)
-	} else {
-		fmt.Printf("Initial collection completed at %s\n", time.Now().Format("15:04:05"))
-	}
-
-	// Start the continuous collection loop
-	collectionCount := 1
-	fmt.Printf("\n Next collection will be at %s\n", time.Now().Add(30*time.Second).Format("15:04:05"))
-
-	for range ticker.C {
-		collectionCount++
-		fmt.Printf("\n=== Metrics collection #%d ===\n", collectionCount)
-		fmt.Printf("Time: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-
-		err = metric_collector.CollectAndStoreMetrics(kubeClient, metricsClient)
-		if err != nil {
-			fmt.Printf("Error collecting metrics: %v\n", err)
-			// Continue running even if one collection fails
-		} else {
-			fmt.Printf("Collection completed successfully\n")
+// DefaultMetricsInterval is how often MetricsCollectorRunnable collects
+// metrics, matching the cadence the old ticker-driven loop used.
+const DefaultMetricsInterval = controllers.DefaultMetricsCollectionInterval
+
+// healthProbeAddr returns the manager's health probe bind address,
+// defaulting to the port the hand-rolled /health server used to listen on.
+func healthProbeAddr() string {
+	if addr := os.Getenv("HEALTH_PROBE_BIND_ADDRESS"); addr != "" {
+		return addr
+	}
+	return ":8080"
+}
+
+// parseClusterKeys builds the list of clusters to monitor. clustersEnv, if
+// set, is a comma-separated list of "resourceGroup/clusterName" pairs
+// (AZURE_CLUSTERS); otherwise it falls back to the single cluster named by
+// AZURE_RESOURCE_GROUP/AZURE_CLUSTER_NAME, as before ClusterCache existed.
+func parseClusterKeys(subscriptionID, clustersEnv, resourceGroup, clusterName string) ([]clustercache.ClusterKey, error) {
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("AZURE_SUBSCRIPTION_ID is required")
+	}
+
+	if clustersEnv == "" {
+		if resourceGroup == "" || clusterName == "" {
+			return nil, fmt.Errorf("set AZURE_CLUSTERS, or both AZURE_RESOURCE_GROUP and AZURE_CLUSTER_NAME")
 		}
+		return []clustercache.ClusterKey{
+			{SubscriptionID: subscriptionID, ResourceGroup: resourceGroup, ClusterName: clusterName},
+		}, nil
+	}
 
-		// Show when next collection will happen
-		nextTime := time.Now().Add(30 * time.Second)
-		fmt.Printf("Next collection at %s\n", nextTime.Format("15:04:05"))
+	var keys []clustercache.ClusterKey
+	for _, pair := range strings.Split(clustersEnv, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid entry %q in AZURE_CLUSTERS, expected resourceGroup/clusterName", pair)
+		}
+		keys = append(keys, clustercache.ClusterKey{SubscriptionID: subscriptionID, ResourceGroup: parts[0], ClusterName: parts[1]})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("AZURE_CLUSTERS was set but contained no entries")
 	}
+	return keys, nil
 }
@@ -2,27 +2,65 @@ package metric_collector
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
+// topNResourceConsumers bounds the top-CPU/top-Mem hotspot slices so a large
+// cluster's per-container breakdown doesn't blow past the ConfigMap's ~1MB
+// size limit.
+const topNResourceConsumers = 10
+
 // MetricsData struct holds all the collected metrics which we will send to the ConfigMap
 type MetricsData struct {
-	Timestamp      string         `json:"timestamp"`
-	PodMetrics     PodMetrics     `json:"pod_metrics"`
-	NodeMetrics    NodeMetrics    `json:"node_metrics"`
-	ContainerStats ContainerStats `json:"container_stats"`
-	ResourceUsage  ResourceUsage  `json:"resource_usage"`
-	ServiceHealth  ServiceHealth  `json:"serviceHealth"`
+	Timestamp        string             `json:"timestamp"`
+	Scope            string             `json:"scope,omitempty"`
+	PodMetrics       PodMetrics         `json:"pod_metrics"`
+	NodeMetrics      NodeMetrics        `json:"node_metrics"`
+	ContainerStats   ContainerStats     `json:"container_stats"`
+	ResourceUsage    ResourceUsage      `json:"resource_usage"`
+	ServiceHealth    ServiceHealth      `json:"serviceHealth"`
+	PodResourceUsage []PodResourceUsage `json:"pod_resource_usage,omitempty"`
+	TopCPUPods       []PodResourceUsage `json:"top_cpu_pods,omitempty"`
+	TopMemPods       []PodResourceUsage `json:"top_mem_pods,omitempty"`
+}
+
+// ProcessState mirrors the Cloud-Foundry/Korifi process_stats.go states,
+// giving a container's lifecycle a coarser label than the raw
+// corev1.ContainerState.
+type ProcessState string
+
+const (
+	ProcessStateStarting ProcessState = "STARTING"
+	ProcessStateRunning  ProcessState = "RUNNING"
+	ProcessStateDown     ProcessState = "DOWN"
+	ProcessStateCrashed  ProcessState = "CRASHED"
+)
+
+// PodResourceUsage is the per-container resource breakdown behind the
+// cluster-wide ResourceUsage percentages, so operators can see which
+// workloads are actually driving utilization.
+type PodResourceUsage struct {
+	Namespace           string       `json:"namespace"`
+	Name                string       `json:"name"`
+	ContainerName       string       `json:"container_name"`
+	CPUMilli            int64        `json:"cpu_milli"`
+	MemBytes            int64        `json:"mem_bytes"`
+	CPUPercentOfRequest float64      `json:"cpu_percent_of_request"`
+	MemPercentOfRequest float64      `json:"mem_percent_of_request"`
+	State               ProcessState `json:"state"`
 }
 
 type PodMetrics struct {
@@ -57,6 +95,15 @@ type ServiceHealth struct {
 	ResponseTime int64  `json:"response_time"`
 	Timestamp    string `json:"timestamp"`
 	ErrorMessage string `json:"error_message,omitempty"`
+
+	// Attempts and response-time distribution across the poll, so a single
+	// transient blip doesn't read the same as a sustained outage.
+	Attempts             int   `json:"attempts"`
+	ConsecutiveSuccesses int   `json:"consecutive_successes"`
+	ConsecutiveFailures  int   `json:"consecutive_failures"`
+	MinResponseTime      int64 `json:"min_response_time_ms"`
+	MaxResponseTime      int64 `json:"max_response_time_ms"`
+	P50ResponseTime      int64 `json:"p50_response_time_ms"`
 }
 
 type ResourceUsage struct {
@@ -64,23 +111,106 @@ type ResourceUsage struct {
 	MemoryUsagePercent float64 `json:"memory_usage_percent"`
 }
 
-// CollectAndStoreMetrics collects all Kubernetes metrics and stores them in the ConfigMap
-func CollectAndStoreMetrics(kubeClient kubernetes.Interface, metricsClient *metrics.Clientset) error {
-	fmt.Println("Starting metrics collection...")
+// lastCollectionSuccess tracks when CollectAndStoreMetrics last completed
+// without error, so the healthz readiness check can detect a stalled
+// collection loop.
+var lastCollectionSuccess atomic.Value // stores time.Time
+
+// RecordCollectionSuccess stamps the current time as the last successful
+// collection. Exported so the healthz package (which cannot import this
+// package's internals) can be wired up from main without a second global.
+func RecordCollectionSuccess() {
+	lastCollectionSuccess.Store(time.Now())
+}
+
+// LastCollectionSuccess returns the time of the most recent successful
+// CollectAndStoreMetrics call, or the zero Time if none has succeeded yet.
+func LastCollectionSuccess() time.Time {
+	if v := lastCollectionSuccess.Load(); v != nil {
+		return v.(time.Time)
+	}
+	return time.Time{}
+}
+
+// MetricsSink receives a copy of every collected MetricsData sample. The
+// Prometheus exporter implements this so collectAndStoreForScope can update the
+// registry without metric_collector importing it back (exporter already
+// depends on metric_collector for the MetricsData type).
+type MetricsSink interface {
+	Update(*MetricsData)
+}
+
+// StorageBackend persists every collected MetricsData sample and allows
+// querying it back afterwards. metric_collector/storage provides ConfigMap,
+// filesystem, and etcd implementations selected at startup via
+// KOPS_STORAGE_BACKEND; this interface lives here, rather than in that
+// package, so storage can depend on metric_collector for the MetricsData
+// type without an import cycle.
+type StorageBackend interface {
+	// AppendMetrics persists one collected sample.
+	AppendMetrics(ctx context.Context, m *MetricsData) error
+	// QueryRange returns every sample with a Timestamp in [from, to].
+	QueryRange(ctx context.Context, from, to time.Time) ([]MetricsData, error)
+	// Latest returns the most recently appended sample, or nil if none exist.
+	Latest(ctx context.Context) (*MetricsData, error)
+}
+
+// disableConfigMapSink, when set via KOPS_DISABLE_CONFIGMAP_SINK, skips the
+// storage backend write so operators who only want the Prometheus sink
+// don't pay for both. The env var predates the pluggable StorageBackend and
+// is kept under its original name for backward compatibility, but it now
+// applies to whichever backend is configured. Off by default so existing
+// deployments are unaffected.
+func disableConfigMapSink() bool {
+	return os.Getenv("KOPS_DISABLE_CONFIGMAP_SINK") == "true"
+}
+
+// CollectAndStoreMetrics collects all Kubernetes metrics and appends them to
+// backend, optionally also pushing them into sink (e.g. the Prometheus
+// exporter) on the same tick. backend and sink may be nil. Collection is
+// split across the scopes in LoadCollectionScopesFromEnv; when more than one
+// scope is configured, each scope's MetricsData is stamped with its scope
+// name so backend can store them without clobbering each other.
+func CollectAndStoreMetrics(kubeClient kubernetes.Interface, metricsClient *metrics.Clientset, backend StorageBackend, sink MetricsSink) error {
+	scopes := LoadCollectionScopesFromEnv()
+
+	for _, scope := range scopes {
+		if err := collectAndStoreForScope(kubeClient, metricsClient, backend, sink, scope, len(scopes) > 1); err != nil {
+			return fmt.Errorf("scope %q: %w", scope.Name, err)
+		}
+	}
+
+	RecordCollectionSuccess()
+	return nil
+}
+
+// collectAndStoreForScope runs one scope's collection and sink/backend
+// writes. multiScope controls whether the sample is stamped with its scope
+// name so backend can separate it from other scopes' samples.
+func collectAndStoreForScope(kubeClient kubernetes.Interface, metricsClient *metrics.Clientset, backend StorageBackend, sink MetricsSink, scope CollectionScope, multiScope bool) error {
+	fmt.Printf("Starting metrics collection for scope %q...\n", scope.Name)
 
-	// Collect all metrics
-	metrics, err := collectMetrics(kubeClient, metricsClient)
+	metrics, err := collectMetrics(kubeClient, metricsClient, scope)
 	if err != nil {
 		return fmt.Errorf("error collecting metrics: %v", err)
 	}
+	if multiScope {
+		metrics.Scope = scope.Name
+	}
 
-	// Update the ConfigMap with the metrics
-	err = updateMetricsConfigMap(kubeClient, metrics)
-	if err != nil {
-		return fmt.Errorf("error updating ConfigMap: %v", err)
+	if sink != nil {
+		sink.Update(metrics)
+	}
+
+	if disableConfigMapSink() {
+		fmt.Printf("Metrics collected (storage sink disabled via KOPS_DISABLE_CONFIGMAP_SINK)\n")
+	} else if backend != nil {
+		if err := backend.AppendMetrics(context.Background(), metrics); err != nil {
+			return fmt.Errorf("error storing metrics: %v", err)
+		}
 	}
 
-	fmt.Printf("Metrics collected and stored in ConfigMap successfully!\n")
+	fmt.Printf("Metrics collected and stored successfully!\n")
 	fmt.Printf("Summary:\n")
 	fmt.Printf("- Pods: %d total, %.1f%% crashing, %.1f%% pending\n",
 		metrics.PodMetrics.TotalPods,
@@ -99,6 +229,8 @@ func CollectAndStoreMetrics(kubeClient kubernetes.Interface, metricsClient *metr
 	fmt.Printf("- Cluster Resource Usage: CPU=%.1f%%, Memory=%.1f%%\n",
 		metrics.ResourceUsage.CPUUsagePercent,
 		metrics.ResourceUsage.MemoryUsagePercent)
+	fmt.Printf("- Top CPU consumers: %d, Top Memory consumers: %d (of %d containers tracked)\n",
+		len(metrics.TopCPUPods), len(metrics.TopMemPods), len(metrics.PodResourceUsage))
 
 	return nil
 }
@@ -110,19 +242,19 @@ func getHealthStatus(healthy bool) string {
 	return "UNHEALTHY"
 }
 
-func collectMetrics(client kubernetes.Interface, metricsClient *metrics.Clientset) (*MetricsData, error) {
+func collectMetrics(client kubernetes.Interface, metricsClient *metrics.Clientset, scope CollectionScope) (*MetricsData, error) {
 	metrics := &MetricsData{
 		Timestamp: time.Now().Format(time.RFC3339),
 	}
 
 	// Collect Pod Metrics
-	podMetrics, err := collectPodMetrics(client)
+	podMetrics, err := collectPodMetrics(client, scope)
 	if err != nil {
 		return nil, fmt.Errorf("failed to collect pod metrics: %v", err)
 	}
 	metrics.PodMetrics = *podMetrics
 
-	// Collect Node Metrics
+	// Collect Node Metrics (nodes aren't namespaced, so scope doesn't apply)
 	nodeMetrics, err := collectNodeMetrics(client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to collect node metrics: %v", err)
@@ -130,7 +262,7 @@ func collectMetrics(client kubernetes.Interface, metricsClient *metrics.Clientse
 	metrics.NodeMetrics = *nodeMetrics
 
 	// Collect Container Stats
-	containerStats, err := collectContainerStats(client)
+	containerStats, err := collectContainerStats(client, scope)
 	if err != nil {
 		return nil, fmt.Errorf("failed to collect container stats: %v", err)
 	}
@@ -141,30 +273,40 @@ func collectMetrics(client kubernetes.Interface, metricsClient *metrics.Clientse
 	metrics.ServiceHealth = *serviceHealth
 
 	// Collect Resource Usage Metrics
-	resourceUsage, err := collectResourceUsageMetrics(metricsClient, client)
+	resourceUsage, err := collectResourceUsageMetrics(metricsClient, client, scope)
 	if err != nil {
 		return nil, fmt.Errorf("failed to collect resource usage metrics: %v", err)
 	}
 	metrics.ResourceUsage = *resourceUsage
 
+	// Collect the per-pod/per-container breakdown behind the cluster-wide
+	// usage percentages above, plus the top-N hotspots for the ConfigMap.
+	podResourceUsage, err := collectPodResourceUsage(metricsClient, client, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect pod resource usage: %v", err)
+	}
+	metrics.PodResourceUsage = podResourceUsage
+	metrics.TopCPUPods = topNByCPU(podResourceUsage, topNResourceConsumers)
+	metrics.TopMemPods = topNByMem(podResourceUsage, topNResourceConsumers)
+
 	return metrics, nil
 
 }
 
-func collectPodMetrics(client kubernetes.Interface) (*PodMetrics, error) {
-	// Get all pods across all namespaces
-	podList, err := client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+func collectPodMetrics(client kubernetes.Interface, scope CollectionScope) (*PodMetrics, error) {
+	// Get all pods in scope (every namespace, with any configured selector)
+	pods, err := listPodsForScope(context.Background(), client, scope)
 	if err != nil {
 		return nil, err
 	}
 
 	metrics := &PodMetrics{
-		TotalPods: len(podList.Items),
+		TotalPods: len(pods),
 	}
 
 	totalRestarts := 0
 
-	for _, pod := range podList.Items {
+	for _, pod := range pods {
 		// Count pod states
 		switch pod.Status.Phase {
 		case corev1.PodPending:
@@ -238,15 +380,15 @@ func collectNodeMetrics(client kubernetes.Interface) (*NodeMetrics, error) {
 	return metrics, nil
 }
 
-func collectContainerStats(client kubernetes.Interface) (*ContainerStats, error) {
-	podList, err := client.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+func collectContainerStats(client kubernetes.Interface, scope CollectionScope) (*ContainerStats, error) {
+	pods, err := listPodsForScope(context.Background(), client, scope)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := &ContainerStats{}
 
-	for _, pod := range podList.Items {
+	for _, pod := range pods {
 		for _, containerStatus := range pod.Status.ContainerStatuses {
 			stats.TotalContainers++
 
@@ -271,6 +413,49 @@ func collectContainerStats(client kubernetes.Interface) (*ContainerStats, error)
 	return stats, nil
 }
 
+// envDuration reads key from the environment and parses it as a
+// time.Duration, falling back to def if unset or unparsable.
+func envDuration(key string, def time.Duration) time.Duration {
+	if raw := os.Getenv(key); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// envInt reads key from the environment and parses it as an int, falling
+// back to def if unset or unparsable.
+func envInt(key string, def int) int {
+	if raw := os.Getenv(key); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// responseTimeStats returns (min, max, p50) over samples in milliseconds,
+// or all zero if samples is empty.
+func responseTimeStats(samples []int64) (min, max, p50 int64) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[0], sorted[len(sorted)-1], sorted[len(sorted)/2]
+}
+
+// CollectServiceHealth polls the customer health URL with bounded retries
+// instead of a single shot, so a transient failure doesn't flip the
+// customer's health for the whole collection window. The poll is governed
+// by CUSTOMER_HEALTH_POLL_INTERVAL (default 1s) and
+// CUSTOMER_HEALTH_POLL_TIMEOUT (default 10s), and the final verdict is
+// promoted/demoted using N-of-M semantics via CUSTOMER_HEALTH_SUCCESS_THRESHOLD
+// (default 1) and CUSTOMER_HEALTH_FAILURE_THRESHOLD (default 3), mirroring
+// kubelet's liveness/readiness probe thresholds.
 func CollectServiceHealth() *ServiceHealth {
 	healthURL := os.Getenv("CUSTOMER_HEALTH_URL")
 
@@ -288,41 +473,88 @@ func CollectServiceHealth() *ServiceHealth {
 		return health
 	}
 
-	// Create HTTP client with 5-second timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	interval := envDuration("CUSTOMER_HEALTH_POLL_INTERVAL", time.Second)
+	timeout := envDuration("CUSTOMER_HEALTH_POLL_TIMEOUT", 10*time.Second)
+	successThreshold := envInt("CUSTOMER_HEALTH_SUCCESS_THRESHOLD", 1)
+	failureThreshold := envInt("CUSTOMER_HEALTH_FAILURE_THRESHOLD", 3)
 
-	startTime := time.Now()
+	// Bound each individual attempt by the poll interval so a slow/hanging
+	// server counts as a failed attempt rather than eating the whole budget.
+	client := &http.Client{Timeout: interval}
 
-	resp, err := client.Get(healthURL)
+	var responseTimes []int64
+	consecutiveSuccesses := 0
+	consecutiveFailures := 0
+	lastErr := ""
+	finalHealthy := false
 
-	responseTime := time.Since(startTime).Milliseconds()
-	health.ResponseTime = responseTime
+	pollErr := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		health.Attempts++
 
-	if err != nil {
-		health.Healthy = false
-		health.ErrorMessage = fmt.Sprintf("Request failed: %v", err)
-		fmt.Printf("Health check failed for %s: %v (response time: %dms)\n", healthURL, err, responseTime)
-		return health
-	}
-	defer resp.Body.Close()
+		startTime := time.Now()
+		resp, err := client.Get(healthURL)
+		responseTime := time.Since(startTime).Milliseconds()
 
-	// Mark healthy if status code is 2xx or 3xx
-	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-		health.Healthy = true
-	} else {
-		health.Healthy = false
-		health.ErrorMessage = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		responseTimes = append(responseTimes, responseTime)
+		health.ResponseTime = responseTime
+
+		success := err == nil && resp.StatusCode >= 200 && resp.StatusCode < 400
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if success {
+			consecutiveSuccesses++
+			consecutiveFailures = 0
+			lastErr = ""
+		} else {
+			consecutiveFailures++
+			consecutiveSuccesses = 0
+			if err != nil {
+				lastErr = fmt.Sprintf("request failed: %v", err)
+			} else {
+				lastErr = fmt.Sprintf("HTTP %d", resp.StatusCode)
+			}
+			fmt.Printf("Health check attempt %d failed for %s: %s (response time: %dms)\n",
+				health.Attempts, healthURL, lastErr, responseTime)
+		}
+
+		health.ConsecutiveSuccesses = consecutiveSuccesses
+		health.ConsecutiveFailures = consecutiveFailures
+
+		if consecutiveSuccesses >= successThreshold {
+			finalHealthy = true
+			return true, nil
+		}
+		if consecutiveFailures >= failureThreshold {
+			finalHealthy = false
+			return true, nil
+		}
+		return false, nil
+	})
+
+	health.Healthy = finalHealthy
+	health.MinResponseTime, health.MaxResponseTime, health.P50ResponseTime = responseTimeStats(responseTimes)
+
+	if !finalHealthy {
+		switch {
+		case pollErr != nil && pollErr != wait.ErrWaitTimeout:
+			health.ErrorMessage = pollErr.Error()
+		case lastErr != "":
+			health.ErrorMessage = lastErr
+		default:
+			health.ErrorMessage = "health check did not reach success threshold before timeout"
+		}
 	}
 
 	return health
 }
 
-func collectResourceUsageMetrics(metricsClient *metrics.Clientset, kubeClient kubernetes.Interface) (*ResourceUsage, error) {
+func collectResourceUsageMetrics(metricsClient *metrics.Clientset, kubeClient kubernetes.Interface, scope CollectionScope) (*ResourceUsage, error) {
 	ctx := context.Background()
 
-	// 1. Get total allocatable resources across all nodes
+	// 1. Get total allocatable resources across all nodes (nodes aren't
+	// namespaced, so the cluster-wide denominator ignores scope)
 	nodeList, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("error getting nodes: %v", err)
@@ -344,8 +576,9 @@ func collectResourceUsageMetrics(metricsClient *metrics.Clientset, kubeClient ku
 		return nil, fmt.Errorf("cluster allocatable capacity is zero — check node metrics or RBAC permissions")
 	}
 
-	// 2. Get pod usage metrics from metrics-server
-	podMetricsList, err := metricsClient.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{})
+	// 2. Get pod usage metrics from metrics-server, scoped to the same
+	// namespaces/selector as the rest of this collection.
+	podMetricsList, err := listPodMetricsForScope(ctx, metricsClient, scope)
 	if err != nil {
 		return nil, fmt.Errorf("error getting pod metrics: %v", err)
 	}
@@ -354,7 +587,7 @@ func collectResourceUsageMetrics(metricsClient *metrics.Clientset, kubeClient ku
 	var totalMemUsed int64 = 0
 
 	// Sum CPU and memory usage for all pods/containers
-	for _, podMetric := range podMetricsList.Items {
+	for _, podMetric := range podMetricsList {
 		for _, containerMetric := range podMetric.Containers {
 			totalCPUUsed += containerMetric.Usage.Cpu().MilliValue() // millicores
 			totalMemUsed += containerMetric.Usage.Memory().Value()   // bytes
@@ -372,91 +605,127 @@ func collectResourceUsageMetrics(metricsClient *metrics.Clientset, kubeClient ku
 	return usage, nil
 }
 
-func updateMetricsConfigMap(client kubernetes.Interface, newMetrics *MetricsData) error {
-	configMapName := "metrics-store"
-	namespace := "default"
-
-	// Get the existing ConfigMap
-	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(
-		context.Background(),
-		configMapName,
-		metav1.GetOptions{},
-	)
-	if err != nil {
-		// If the ConfigMap does not exist, create it
-		configMap = &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      configMapName,
-				Namespace: namespace,
-			},
-			Data: map[string]string{},
+// determineProcessState classifies a container's lifecycle into the
+// Cloud-Foundry-style states used by Korifi's process_stats.go: RUNNING
+// when ready and actually running, STARTING while the kubelet is still
+// pulling/creating/initializing it, CRASHED when it's in a crash loop or
+// its last termination was a non-zero exit, and DOWN otherwise.
+func determineProcessState(cs corev1.ContainerStatus) ProcessState {
+	if cs.State.Waiting != nil {
+		switch cs.State.Waiting.Reason {
+		case "ContainerCreating", "PodInitializing":
+			return ProcessStateStarting
+		case "CrashLoopBackOff":
+			return ProcessStateCrashed
 		}
+	}
 
-		_, err := client.CoreV1().ConfigMaps(namespace).Create(
-			context.Background(),
-			configMap,
-			metav1.CreateOptions{},
-		)
-		if err != nil {
-			return fmt.Errorf("failed to create ConfigMap: %v", err)
-		}
+	if cs.LastTerminationState.Terminated != nil && cs.LastTerminationState.Terminated.ExitCode != 0 {
+		return ProcessStateCrashed
 	}
 
-	// Initialize data map if it doesn't exist
-	if configMap.Data == nil {
-		configMap.Data = make(map[string]string)
+	if cs.Ready && cs.State.Running != nil {
+		return ProcessStateRunning
 	}
 
-	// Get existing metrics history
-	var metricsHistory []MetricsData
-	if existingHistory, exists := configMap.Data["metrics_history.json"]; exists && existingHistory != "" {
-		err = json.Unmarshal([]byte(existingHistory), &metricsHistory)
-		if err != nil {
-			// If we can't parse existing history, start fresh but log the issue
-			fmt.Printf("Warning: Could not parse existing metrics history, starting fresh: %v\n", err)
-			metricsHistory = []MetricsData{}
-		}
+	return ProcessStateDown
+}
+
+// collectPodResourceUsage joins live usage from the metrics-server against
+// each pod's container requests and status, producing a per-container
+// breakdown of what's driving the cluster-wide ResourceUsage percentages.
+func collectPodResourceUsage(metricsClient *metrics.Clientset, kubeClient kubernetes.Interface, scope CollectionScope) ([]PodResourceUsage, error) {
+	ctx := context.Background()
+
+	pods, err := listPodsForScope(ctx, kubeClient, scope)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pods: %v", err)
 	}
 
-	// Append new metrics to history
-	metricsHistory = append(metricsHistory, *newMetrics)
+	// Index requests and container statuses by (namespace, pod, container)
+	// so the metrics-server usage list below can be joined against them.
+	type podInfo struct {
+		requests map[string]corev1.ResourceList
+		statuses map[string]corev1.ContainerStatus
+	}
+	podsByKey := make(map[string]podInfo, len(pods))
 
-	// Keep only the last 100 entries to prevent ConfigMap from growing too large (ConfigMaps have a size limit of ~1MB)
-	maxHistoryEntries := 100
-	if len(metricsHistory) > maxHistoryEntries {
-		// Keep the most recent entries
-		metricsHistory = metricsHistory[len(metricsHistory)-maxHistoryEntries:]
-		fmt.Printf("Trimmed metrics history to last %d entries\n", maxHistoryEntries)
+	for _, pod := range pods {
+		info := podInfo{
+			requests: make(map[string]corev1.ResourceList, len(pod.Spec.Containers)),
+			statuses: make(map[string]corev1.ContainerStatus, len(pod.Status.ContainerStatuses)),
+		}
+		for _, c := range pod.Spec.Containers {
+			info.requests[c.Name] = c.Resources.Requests
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			info.statuses[cs.Name] = cs
+		}
+		podsByKey[pod.Namespace+"/"+pod.Name] = info
 	}
 
-	// Convert history back to JSON
-	historyJSON, err := json.MarshalIndent(metricsHistory, "", "  ")
+	podMetricsList, err := listPodMetricsForScope(ctx, metricsClient, scope)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metrics history: %v", err)
+		return nil, fmt.Errorf("error getting pod metrics: %v", err)
 	}
 
-	// Convert current metrics to JSON
-	currentMetricsJSON, err := json.MarshalIndent(newMetrics, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal current metrics: %v", err)
+	usage := make([]PodResourceUsage, 0, len(podMetricsList))
+	for _, podMetric := range podMetricsList {
+		info, ok := podsByKey[podMetric.Namespace+"/"+podMetric.Name]
+		if !ok {
+			continue // pod has usage but was gone by the time we listed pods
+		}
+
+		for _, containerMetric := range podMetric.Containers {
+			cpuMilli := containerMetric.Usage.Cpu().MilliValue()
+			memBytes := containerMetric.Usage.Memory().Value()
+
+			pru := PodResourceUsage{
+				Namespace:     podMetric.Namespace,
+				Name:          podMetric.Name,
+				ContainerName: containerMetric.Name,
+				CPUMilli:      cpuMilli,
+				MemBytes:      memBytes,
+				State:         ProcessStateDown,
+			}
+
+			if requests, ok := info.requests[containerMetric.Name]; ok {
+				if cpuReq := requests.Cpu().MilliValue(); cpuReq > 0 {
+					pru.CPUPercentOfRequest = math.Round(float64(cpuMilli)/float64(cpuReq)*100*100) / 100
+				}
+				if memReq := requests.Memory().Value(); memReq > 0 {
+					pru.MemPercentOfRequest = math.Round(float64(memBytes)/float64(memReq)*100*100) / 100
+				}
+			}
+
+			if status, ok := info.statuses[containerMetric.Name]; ok {
+				pru.State = determineProcessState(status)
+			}
+
+			usage = append(usage, pru)
+		}
 	}
 
-	// Update ConfigMap with both current metrics and history
-	configMap.Data["current_metrics.json"] = string(currentMetricsJSON)
-	configMap.Data["metrics_history.json"] = string(historyJSON)
-	configMap.Data["last_updated"] = time.Now().UTC().Format(time.RFC3339)
-	configMap.Data["total_collections"] = fmt.Sprintf("%d", len(metricsHistory))
+	return usage, nil
+}
 
-	// Update the ConfigMap in the cluster
-	_, err = client.CoreV1().ConfigMaps(namespace).Update(
-		context.Background(),
-		configMap,
-		metav1.UpdateOptions{},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update ConfigMap: %v", err)
+// topNByCPU returns the n highest-CPU entries in usage, descending.
+func topNByCPU(usage []PodResourceUsage, n int) []PodResourceUsage {
+	sorted := append([]PodResourceUsage(nil), usage...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CPUMilli > sorted[j].CPUMilli })
+	if len(sorted) > n {
+		sorted = sorted[:n]
 	}
+	return sorted
+}
 
-	fmt.Printf("Metrics appended to history (total collections: %d)\n", len(metricsHistory))
-	return nil
+// topNByMem returns the n highest-memory entries in usage, descending.
+func topNByMem(usage []PodResourceUsage, n int) []PodResourceUsage {
+	sorted := append([]PodResourceUsage(nil), usage...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MemBytes > sorted[j].MemBytes })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
 }
+
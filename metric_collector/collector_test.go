@@ -0,0 +1,175 @@
+package metric_collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setFastPollEnv configures a short poll interval/timeout so retry-driven
+// tests don't pay the 1s/10s production defaults, restoring the previous
+// environment on cleanup.
+func setFastPollEnv(t *testing.T, failureThreshold, successThreshold string) {
+	t.Helper()
+
+	vars := map[string]string{
+		"CUSTOMER_HEALTH_POLL_INTERVAL":     "20ms",
+		"CUSTOMER_HEALTH_POLL_TIMEOUT":      "300ms",
+		"CUSTOMER_HEALTH_FAILURE_THRESHOLD": failureThreshold,
+		"CUSTOMER_HEALTH_SUCCESS_THRESHOLD": successThreshold,
+	}
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	t.Cleanup(func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	})
+}
+
+// TestCollectServiceHealth_Healthy verifies a single 200 response satisfies
+// the default success threshold of 1.
+func TestCollectServiceHealth_Healthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("CUSTOMER_HEALTH_URL", server.URL)
+	defer os.Unsetenv("CUSTOMER_HEALTH_URL")
+
+	result := CollectServiceHealth()
+
+	if !result.Healthy {
+		t.Errorf("Expected healthy, got unhealthy: %v", result.ErrorMessage)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("Expected a single attempt to satisfy the default success threshold, got %d", result.Attempts)
+	}
+}
+
+// TestCollectServiceHealth_Unhealthy verifies an endpoint that returns 500
+// on every attempt is demoted once the failure threshold is reached.
+func TestCollectServiceHealth_Unhealthy(t *testing.T) {
+	setFastPollEnv(t, "2", "1")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	os.Setenv("CUSTOMER_HEALTH_URL", server.URL)
+	defer os.Unsetenv("CUSTOMER_HEALTH_URL")
+
+	result := CollectServiceHealth()
+
+	if result.Healthy {
+		t.Errorf("Expected unhealthy, got healthy")
+	}
+	if result.ConsecutiveFailures < 2 {
+		t.Errorf("Expected the failure threshold to be reached, got %d consecutive failures", result.ConsecutiveFailures)
+	}
+}
+
+// TestCollectServiceHealth_Timeout simulates every attempt being slower than
+// the poll interval, which is used as the per-request timeout.
+func TestCollectServiceHealth_Timeout(t *testing.T) {
+	setFastPollEnv(t, "2", "1")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond) // exceeds the 20ms poll interval used as the request timeout
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("CUSTOMER_HEALTH_URL", server.URL)
+	defer os.Unsetenv("CUSTOMER_HEALTH_URL")
+
+	result := CollectServiceHealth()
+
+	if result.Healthy {
+		t.Errorf("Expected timeout failure, got healthy")
+	}
+	if result.Attempts == 0 {
+		t.Errorf("Expected at least one attempt to be recorded")
+	}
+}
+
+// TestCollectServiceHealth_RecoversAfterFailure covers a flaky endpoint:
+// the first call fails, then succeeds enough times to cross the success
+// threshold and promote the service back to healthy.
+func TestCollectServiceHealth_RecoversAfterFailure(t *testing.T) {
+	setFastPollEnv(t, "5", "1")
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("CUSTOMER_HEALTH_URL", server.URL)
+	defer os.Unsetenv("CUSTOMER_HEALTH_URL")
+
+	result := CollectServiceHealth()
+
+	if !result.Healthy {
+		t.Errorf("Expected the second, successful attempt to promote the service back to healthy: %v", result.ErrorMessage)
+	}
+	if result.Attempts < 2 {
+		t.Errorf("Expected at least 2 attempts (one failure, one success), got %d", result.Attempts)
+	}
+}
+
+// TestCollectServiceHealth_PromotesAfterSuccessThreshold verifies a service
+// whose first check fails only promotes to healthy once
+// CUSTOMER_HEALTH_SUCCESS_THRESHOLD consecutive 200s have been observed,
+// not on the first one. The failure threshold is 2 so the single 500 does
+// not end the poll as unhealthy.
+func TestCollectServiceHealth_PromotesAfterSuccessThreshold(t *testing.T) {
+	setFastPollEnv(t, "2", "2")
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("CUSTOMER_HEALTH_URL", server.URL)
+	defer os.Unsetenv("CUSTOMER_HEALTH_URL")
+
+	result := CollectServiceHealth()
+
+	if !result.Healthy {
+		t.Errorf("Expected eventual promotion to healthy once 2 consecutive successes were observed: %v", result.ErrorMessage)
+	}
+	if result.ConsecutiveSuccesses < 2 {
+		t.Errorf("Expected at least 2 consecutive successes, got %d", result.ConsecutiveSuccesses)
+	}
+	if result.Attempts < 3 {
+		t.Errorf("Expected at least 3 attempts (1 failure + 2 successes), got %d", result.Attempts)
+	}
+}
+
+// TestCollectServiceHealth_NoURL verifies the no-URL-configured case is
+// reported as healthy with an explanatory message, rather than as a failure.
+func TestCollectServiceHealth_NoURL(t *testing.T) {
+	os.Unsetenv("CUSTOMER_HEALTH_URL")
+
+	result := CollectServiceHealth()
+
+	if !result.Healthy || result.ErrorMessage != "No health URL configured" {
+		t.Errorf("Expected healthy with note about missing URL")
+	}
+}
@@ -0,0 +1,167 @@
+// Package exporter exposes the fields of metric_collector.MetricsData as
+// Prometheus gauges and serves them on a /metrics endpoint, following the
+// pattern kubelet uses in server.InstallDefaultHandlers to mount
+// prometheus.Handler() on its restful container.
+package exporter
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"kops/metric_collector"
+)
+
+// DefaultBindAddr is used when no address is supplied to Start.
+const DefaultBindAddr = ":9090"
+
+// Exporter owns a Prometheus registry populated from MetricsData and the
+// HTTP server that serves it.
+type Exporter struct {
+	registry *prometheus.Registry
+
+	podsTotal          prometheus.Gauge
+	podsCrashing       prometheus.Gauge
+	podsPending        prometheus.Gauge
+	podsRunning        prometheus.Gauge
+	podsRestartsTotal  prometheus.Gauge
+	nodesTotal         prometheus.Gauge
+	nodesReady         prometheus.Gauge
+	nodesNotReady      prometheus.Gauge
+	containersTotal    prometheus.Gauge
+	containersCrashing prometheus.Gauge
+	serviceHealthy     prometheus.Gauge
+	serviceRespMillis  prometheus.Gauge
+	cpuUsagePercent    prometheus.Gauge
+	memUsagePercent    prometheus.Gauge
+}
+
+// New creates an Exporter with one gauge for each count field of
+// PodMetrics, NodeMetrics and ContainerStats, for ServiceHealth's Healthy
+// and ResponseTime, and for ResourceUsage's CPU and memory percentages. The
+// percentage fields of PodMetrics, NodeMetrics and ContainerStats, the other
+// ServiceHealth fields, and the per-pod slices are not exported.
+func New() *Exporter {
+	registry := prometheus.NewRegistry()
+
+	e := &Exporter{
+		registry: registry,
+		podsTotal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_pods_total", Help: "Total number of pods observed in the last collection.",
+		}),
+		podsCrashing: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_pods_crashing", Help: "Number of pods considered crashing in the last collection.",
+		}),
+		podsPending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_pods_pending", Help: "Number of pending pods in the last collection.",
+		}),
+		podsRunning: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_pods_running", Help: "Number of running pods in the last collection.",
+		}),
+		podsRestartsTotal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_pod_restarts_total", Help: "Total container restarts summed across all pods.",
+		}),
+		nodesTotal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_nodes_total", Help: "Total number of nodes observed in the last collection.",
+		}),
+		nodesReady: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_nodes_ready", Help: "Number of Ready nodes in the last collection.",
+		}),
+		nodesNotReady: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_nodes_not_ready", Help: "Number of NotReady nodes in the last collection.",
+		}),
+		containersTotal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_containers_total", Help: "Total number of containers observed in the last collection.",
+		}),
+		containersCrashing: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_containers_crash_looping", Help: "Number of containers in CrashLoopBackOff or with more than 5 restarts in the last collection; a container matching both counts twice.",
+		}),
+		serviceHealthy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_customer_service_healthy", Help: "1 if the customer health URL was reachable and healthy, 0 otherwise.",
+		}),
+		serviceRespMillis: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_customer_service_response_ms", Help: "Response time of the customer health check in milliseconds.",
+		}),
+		cpuUsagePercent: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_cluster_cpu_usage_percent", Help: "Cluster-wide CPU usage as a percentage of allocatable capacity.",
+		}),
+		memUsagePercent: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kops_cluster_memory_usage_percent", Help: "Cluster-wide memory usage as a percentage of allocatable capacity.",
+		}),
+	}
+
+	registry.MustRegister(
+		e.podsTotal, e.podsCrashing, e.podsPending, e.podsRunning, e.podsRestartsTotal,
+		e.nodesTotal, e.nodesReady, e.nodesNotReady,
+		e.containersTotal, e.containersCrashing,
+		e.serviceHealthy, e.serviceRespMillis,
+		e.cpuUsagePercent, e.memUsagePercent,
+	)
+
+	return e
+}
+
+// Update pushes the latest MetricsData into the Prometheus registry.
+func (e *Exporter) Update(m *metric_collector.MetricsData) {
+	if m == nil {
+		return
+	}
+
+	e.podsTotal.Set(float64(m.PodMetrics.TotalPods))
+	e.podsCrashing.Set(float64(m.PodMetrics.CrashingPods))
+	e.podsPending.Set(float64(m.PodMetrics.PendingPods))
+	e.podsRunning.Set(float64(m.PodMetrics.RunningPods))
+	e.podsRestartsTotal.Set(float64(m.PodMetrics.TotalRestarts))
+
+	e.nodesTotal.Set(float64(m.NodeMetrics.TotalNodes))
+	e.nodesReady.Set(float64(m.NodeMetrics.ReadyNodes))
+	e.nodesNotReady.Set(float64(m.NodeMetrics.NotReadyNodes))
+
+	e.containersTotal.Set(float64(m.ContainerStats.TotalContainers))
+	e.containersCrashing.Set(float64(m.ContainerStats.CrashLoopContainers))
+
+	if m.ServiceHealth.Healthy {
+		e.serviceHealthy.Set(1)
+	} else {
+		e.serviceHealthy.Set(0)
+	}
+	e.serviceRespMillis.Set(float64(m.ServiceHealth.ResponseTime))
+
+	e.cpuUsagePercent.Set(m.ResourceUsage.CPUUsagePercent)
+	e.memUsagePercent.Set(m.ResourceUsage.MemoryUsagePercent)
+}
+
+// Handler returns the http.Handler that serves the registry, suitable for
+// mounting on a shared mux alongside /healthz and /readyz.
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterOn mounts the exporter's Handler on /metrics on mux, so callers
+// that also serve /healthz and /readyz can share one listener.
+func (e *Exporter) RegisterOn(mux *http.ServeMux) {
+	mux.Handle("/metrics", e.Handler())
+}
+
+// Start mounts the exporter's Handler on /metrics and serves it on addr.
+// It blocks, so callers should run it in a goroutine; if addr is empty,
+// DefaultBindAddr is used. Callers that also need /healthz and /readyz on
+// the same listener should build their own mux, call RegisterOn, and serve
+// it themselves instead of using Start.
+func (e *Exporter) Start(addr string) error {
+	if addr == "" {
+		addr = DefaultBindAddr
+	}
+
+	mux := http.NewServeMux()
+	e.RegisterOn(mux)
+
+	log.Printf("Prometheus metrics endpoint running on %s/metrics\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		return fmt.Errorf("failed to start metrics server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package healthz provides a small liveness/readiness check framework
+// modeled on k8s.io/apiserver/pkg/server/healthz (the same pattern kubelet
+// uses via healthz.InstallHandler(mux, PingHealthz, NamedCheck(...))), so
+// this controller's own pod can be probed by Kubernetes.
+package healthz
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HealthChecker is a single named check that can fail a /healthz or /readyz
+// probe.
+type HealthChecker interface {
+	Name() string
+	Check(req *http.Request) error
+}
+
+type namedCheck struct {
+	name  string
+	check func(r *http.Request) error
+}
+
+func (c *namedCheck) Name() string                { return c.name }
+func (c *namedCheck) Check(r *http.Request) error { return c.check(r) }
+
+// NamedCheck returns a HealthChecker that reports failures under the given
+// name.
+func NamedCheck(name string, check func(r *http.Request) error) HealthChecker {
+	return &namedCheck{name: name, check: check}
+}
+
+// PingHealthz is a trivial HealthChecker that always succeeds, used as the
+// sole check behind /healthz (liveness) so the process only fails liveness
+// when it has stopped serving HTTP at all.
+var PingHealthz HealthChecker = NamedCheck("ping", func(_ *http.Request) error { return nil })
+
+// InstallHandler registers path on mux, returning 200 only when every check
+// passes and 503 with the list of failures otherwise.
+func InstallHandler(mux *http.ServeMux, path string, checks ...HealthChecker) {
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		var failed []string
+		for _, check := range checks {
+			if err := check.Check(r); err != nil {
+				failed = append(failed, fmt.Sprintf("[-]%s failed: %v", check.Name(), err))
+			}
+		}
+
+		if len(failed) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			for _, msg := range failed {
+				fmt.Fprintln(w, msg)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+}
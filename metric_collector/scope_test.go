@@ -0,0 +1,124 @@
+package metric_collector
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+// TestListPodsForScope_Unscoped verifies an empty CollectionScope lists
+// every pod across every namespace, preserving the pre-scope behavior.
+func TestListPodsForScope_Unscoped(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("default", "a"),
+		newPod("kube-system", "b"),
+		newPod("customer", "c"),
+	)
+
+	pods, err := listPodsForScope(context.Background(), client, CollectionScope{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 3 {
+		t.Errorf("expected 3 pods across all namespaces, got %d", len(pods))
+	}
+}
+
+// TestListPodsForScope_SingleNamespace verifies a scope with one namespace
+// only returns pods from that namespace.
+func TestListPodsForScope_SingleNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("default", "a"),
+		newPod("kube-system", "b"),
+		newPod("customer", "c"),
+	)
+
+	scope := CollectionScope{Name: "customer", Namespaces: []string{"customer"}}
+	pods, err := listPodsForScope(context.Background(), client, scope)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 1 || pods[0].Name != "c" {
+		t.Errorf("expected only pod 'c' from namespace 'customer', got %v", pods)
+	}
+}
+
+// TestListPodsForScope_MultiNamespace verifies a scope listing several
+// namespaces aggregates their pods together.
+func TestListPodsForScope_MultiNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("default", "a"),
+		newPod("kube-system", "b"),
+		newPod("customer", "c"),
+	)
+
+	scope := CollectionScope{Name: "multi", Namespaces: []string{"default", "customer"}}
+	pods, err := listPodsForScope(context.Background(), client, scope)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 2 {
+		t.Errorf("expected 2 pods from 'default' and 'customer', got %d", len(pods))
+	}
+}
+
+// TestLoadCollectionScopesFromEnv_Unset verifies an unset KOPS_NAMESPACES
+// yields a single unscoped "default" scope, preserving pre-scope behavior.
+func TestLoadCollectionScopesFromEnv_Unset(t *testing.T) {
+	os.Unsetenv("KOPS_NAMESPACES")
+
+	scopes := LoadCollectionScopesFromEnv()
+	if len(scopes) != 1 || scopes[0].Name != defaultScopeName || len(scopes[0].Namespaces) != 0 {
+		t.Errorf("expected a single unscoped %q scope, got %+v", defaultScopeName, scopes)
+	}
+}
+
+// TestLoadCollectionScopesFromEnv_PlainList verifies the original,
+// single-scope form of KOPS_NAMESPACES (a plain comma-separated list, no
+// scope name) still produces one "default" scope.
+func TestLoadCollectionScopesFromEnv_PlainList(t *testing.T) {
+	os.Setenv("KOPS_NAMESPACES", "prod-a,prod-b")
+	defer os.Unsetenv("KOPS_NAMESPACES")
+
+	scopes := LoadCollectionScopesFromEnv()
+	if len(scopes) != 1 {
+		t.Fatalf("expected 1 scope, got %d: %+v", len(scopes), scopes)
+	}
+	if scopes[0].Name != defaultScopeName {
+		t.Errorf("expected scope named %q, got %q", defaultScopeName, scopes[0].Name)
+	}
+	if len(scopes[0].Namespaces) != 2 {
+		t.Errorf("expected 2 namespaces, got %v", scopes[0].Namespaces)
+	}
+}
+
+// TestLoadCollectionScopesFromEnv_Named verifies the "name:ns1,ns2;..." form
+// of KOPS_NAMESPACES produces one named, separately-keyed scope per group.
+func TestLoadCollectionScopesFromEnv_Named(t *testing.T) {
+	os.Setenv("KOPS_NAMESPACES", "prod:prod-a,prod-b;staging:staging-a")
+	defer os.Unsetenv("KOPS_NAMESPACES")
+
+	scopes := LoadCollectionScopesFromEnv()
+	if len(scopes) != 2 {
+		t.Fatalf("expected 2 named scopes, got %d: %+v", len(scopes), scopes)
+	}
+	if scopes[0].Name != "prod" || len(scopes[0].Namespaces) != 2 {
+		t.Errorf("expected prod scope with 2 namespaces, got %+v", scopes[0])
+	}
+	if scopes[1].Name != "staging" || len(scopes[1].Namespaces) != 1 {
+		t.Errorf("expected staging scope with 1 namespace, got %+v", scopes[1])
+	}
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"kops/metric_collector"
+)
+
+const (
+	configMapName     = "metrics-store"
+	maxHistoryEntries = 100 // ConfigMaps have a size limit of ~1MB
+)
+
+// ConfigMapBackend is the original storage model: one ConfigMap holding the
+// current sample and a capped history per scope. It's the default backend
+// since it needs no extra infrastructure, but QueryRange/Latest pay for a
+// full ConfigMap fetch and JSON unmarshal on every call.
+type ConfigMapBackend struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapBackend returns a ConfigMapBackend writing to the
+// "metrics-store" ConfigMap in namespace, which defaults to "default" (or
+// KOPS_CONFIGMAP_NAMESPACE if set) when namespace is empty.
+func NewConfigMapBackend(client kubernetes.Interface, namespace string) *ConfigMapBackend {
+	if namespace == "" {
+		namespace = "default"
+		if env := os.Getenv("KOPS_CONFIGMAP_NAMESPACE"); env != "" {
+			namespace = env
+		}
+	}
+	return &ConfigMapBackend{client: client, namespace: namespace}
+}
+
+// scopeKeys returns the ConfigMap keys used to store a scope's current
+// metrics, metrics history, last-updated timestamp, and collection count.
+// The default/unscoped sample (scope == "") keeps the original unsuffixed
+// keys so existing consumers aren't broken; every named scope gets a
+// "<name>." prefix so scopes don't clobber each other.
+func scopeKeys(scope string) (current, history, lastUpdated, totalCollections string) {
+	if scope == "" {
+		return "current_metrics.json", "metrics_history.json", "last_updated", "total_collections"
+	}
+	prefix := scope + "."
+	return prefix + "current_metrics.json", prefix + "metrics_history.json", prefix + "last_updated", prefix + "total_collections"
+}
+
+func (b *ConfigMapBackend) getOrCreateConfigMap(ctx context.Context) (*corev1.ConfigMap, error) {
+	configMap, err := b.client.CoreV1().ConfigMaps(b.namespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: b.namespace},
+			Data:       map[string]string{},
+		}
+		return b.client.CoreV1().ConfigMaps(b.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	return configMap, nil
+}
+
+func (b *ConfigMapBackend) AppendMetrics(ctx context.Context, m *metric_collector.MetricsData) error {
+	configMap, err := b.getOrCreateConfigMap(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get/create ConfigMap: %v", err)
+	}
+
+	currentKey, historyKey, lastUpdatedKey, totalKey := scopeKeys(m.Scope)
+
+	var history []metric_collector.MetricsData
+	if existing, ok := configMap.Data[historyKey]; ok && existing != "" {
+		if err := json.Unmarshal([]byte(existing), &history); err != nil {
+			fmt.Printf("Warning: Could not parse existing metrics history, starting fresh: %v\n", err)
+			history = nil
+		}
+	}
+
+	history = append(history, *m)
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+		fmt.Printf("Trimmed metrics history to last %d entries\n", maxHistoryEntries)
+	}
+
+	historyJSON, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics history: %v", err)
+	}
+	currentJSON, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal current metrics: %v", err)
+	}
+
+	configMap.Data[currentKey] = string(currentJSON)
+	configMap.Data[historyKey] = string(historyJSON)
+	configMap.Data[lastUpdatedKey] = time.Now().UTC().Format(time.RFC3339)
+	configMap.Data[totalKey] = fmt.Sprintf("%d", len(history))
+
+	if _, err := b.client.CoreV1().ConfigMaps(b.namespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap: %v", err)
+	}
+
+	fmt.Printf("Metrics appended to history (total collections: %d)\n", len(history))
+	return nil
+}
+
+func (b *ConfigMapBackend) QueryRange(ctx context.Context, from, to time.Time) ([]metric_collector.MetricsData, error) {
+	configMap, err := b.getOrCreateConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap: %v", err)
+	}
+
+	var inRange []metric_collector.MetricsData
+	for key, raw := range configMap.Data {
+		if key != "metrics_history.json" && !strings.HasSuffix(key, ".metrics_history.json") {
+			continue
+		}
+		var history []metric_collector.MetricsData
+		if err := json.Unmarshal([]byte(raw), &history); err != nil {
+			continue
+		}
+		for _, m := range history {
+			ts, err := time.Parse(time.RFC3339, m.Timestamp)
+			if err != nil {
+				continue
+			}
+			if !ts.Before(from) && !ts.After(to) {
+				inRange = append(inRange, m)
+			}
+		}
+	}
+	return inRange, nil
+}
+
+func (b *ConfigMapBackend) Latest(ctx context.Context) (*metric_collector.MetricsData, error) {
+	configMap, err := b.getOrCreateConfigMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap: %v", err)
+	}
+
+	var latest *metric_collector.MetricsData
+	var latestTS time.Time
+	for key, raw := range configMap.Data {
+		if (key != "current_metrics.json" && !strings.HasSuffix(key, ".current_metrics.json")) || raw == "" {
+			continue
+		}
+		var m metric_collector.MetricsData
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			return nil, fmt.Errorf("failed to parse current metrics: %v", err)
+		}
+		ts, _ := time.Parse(time.RFC3339, m.Timestamp)
+		if latest == nil || ts.After(latestTS) {
+			latest, latestTS = &m, ts
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"kops/metric_collector"
+)
+
+// TestConfigMapBackend_AppendAndLatest verifies a round-trip through the
+// default (unscoped) ConfigMap keys.
+func TestConfigMapBackend_AppendAndLatest(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	backend := NewConfigMapBackend(client, "default")
+
+	m := &metric_collector.MetricsData{Timestamp: time.Now().UTC().Format(time.RFC3339)}
+	if err := backend.AppendMetrics(context.Background(), m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	latest, err := backend.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if latest == nil || latest.Timestamp != m.Timestamp {
+		t.Errorf("expected latest sample to match appended sample, got %+v", latest)
+	}
+}
+
+// TestConfigMapBackend_ScopedKeysDontClobber verifies two scopes writing to
+// the same ConfigMap keep independent history instead of overwriting
+// each other's keys.
+func TestConfigMapBackend_ScopedKeysDontClobber(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	backend := NewConfigMapBackend(client, "default")
+	ctx := context.Background()
+
+	a := &metric_collector.MetricsData{Timestamp: time.Now().UTC().Format(time.RFC3339), Scope: "customer-a"}
+	b := &metric_collector.MetricsData{Timestamp: time.Now().UTC().Format(time.RFC3339), Scope: "customer-b"}
+
+	if err := backend.AppendMetrics(ctx, a); err != nil {
+		t.Fatalf("unexpected error appending scope a: %v", err)
+	}
+	if err := backend.AppendMetrics(ctx, b); err != nil {
+		t.Fatalf("unexpected error appending scope b: %v", err)
+	}
+
+	results, err := backend.QueryRange(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected samples from both scopes, got %d", len(results))
+	}
+}
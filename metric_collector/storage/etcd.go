@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"kops/metric_collector"
+)
+
+// etcdKeyPrefix namespaces every sample this backend writes, under
+// /kops/metrics/<scope>/<rfc3339nano>.
+const etcdKeyPrefix = "/kops/metrics/"
+
+// EtcdBackend stores each sample as its own etcd key so old samples expire
+// on their own via a TTL lease, instead of needing a separate GC pass like
+// the ConfigMap backend's history trimming.
+type EtcdBackend struct {
+	client *clientv3.Client
+	ttl    time.Duration
+}
+
+// NewEtcdBackend returns an EtcdBackend writing through client. Samples are
+// leased for ttl and expire automatically; ttl <= 0 means keep forever.
+func NewEtcdBackend(client *clientv3.Client, ttl time.Duration) *EtcdBackend {
+	return &EtcdBackend{client: client, ttl: ttl}
+}
+
+func etcdKey(scope string, at time.Time) string {
+	if scope == "" {
+		scope = "default"
+	}
+	return etcdKeyPrefix + scope + "/" + at.UTC().Format(time.RFC3339Nano)
+}
+
+func (b *EtcdBackend) AppendMetrics(ctx context.Context, m *metric_collector.MetricsData) error {
+	value, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %v", err)
+	}
+
+	var opts []clientv3.OpOption
+	if b.ttl > 0 {
+		lease, err := b.client.Grant(ctx, int64(b.ttl.Seconds()))
+		if err != nil {
+			return fmt.Errorf("failed to create etcd lease: %v", err)
+		}
+		opts = append(opts, clientv3.WithLease(lease.ID))
+	}
+
+	if _, err := b.client.Put(ctx, etcdKey(m.Scope, time.Now()), string(value), opts...); err != nil {
+		return fmt.Errorf("failed to put metrics into etcd: %v", err)
+	}
+	return nil
+}
+
+func (b *EtcdBackend) QueryRange(ctx context.Context, from, to time.Time) ([]metric_collector.MetricsData, error) {
+	resp, err := b.client.Get(ctx, etcdKeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metrics from etcd: %v", err)
+	}
+
+	var inRange []metric_collector.MetricsData
+	for _, kv := range resp.Kvs {
+		var m metric_collector.MetricsData
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, m.Timestamp)
+		if err != nil {
+			continue
+		}
+		if !ts.Before(from) && !ts.After(to) {
+			inRange = append(inRange, m)
+		}
+	}
+	return inRange, nil
+}
+
+func (b *EtcdBackend) Latest(ctx context.Context) (*metric_collector.MetricsData, error) {
+	resp, err := b.client.Get(ctx, etcdKeyPrefix, clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend), clientv3.WithLimit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest metrics from etcd: %v", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var m metric_collector.MetricsData
+	if err := json.Unmarshal(resp.Kvs[0].Value, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse latest metrics: %v", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/client-go/kubernetes"
+
+	"kops/metric_collector"
+)
+
+// NewFromEnv constructs the StorageBackend selected by KOPS_STORAGE_BACKEND
+// ("configmap", "file", or "etcd"; defaults to "configmap" so existing
+// deployments are unaffected).
+func NewFromEnv(kubeClient kubernetes.Interface) (metric_collector.StorageBackend, error) {
+	switch backend := os.Getenv("KOPS_STORAGE_BACKEND"); backend {
+	case "", "configmap":
+		return NewConfigMapBackend(kubeClient, os.Getenv("KOPS_CONFIGMAP_NAMESPACE")), nil
+
+	case "file":
+		dir := os.Getenv("KOPS_FILE_STORAGE_DIR")
+		if dir == "" {
+			dir = "/var/lib/kops/metrics"
+		}
+		return NewFileBackend(dir), nil
+
+	case "etcd":
+		endpoints := strings.Split(os.Getenv("KOPS_ETCD_ENDPOINTS"), ",")
+		if len(endpoints) == 0 || endpoints[0] == "" {
+			return nil, fmt.Errorf("KOPS_ETCD_ENDPOINTS must be set when KOPS_STORAGE_BACKEND=etcd")
+		}
+
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   endpoints,
+			DialTimeout: 5 * time.Second,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create etcd client: %v", err)
+		}
+
+		ttl := 7 * 24 * time.Hour
+		if raw := os.Getenv("KOPS_ETCD_METRICS_TTL"); raw != "" {
+			if d, err := time.ParseDuration(raw); err == nil {
+				ttl = d
+			}
+		}
+
+		return NewEtcdBackend(client, ttl), nil
+
+	default:
+		return nil, fmt.Errorf("unknown KOPS_STORAGE_BACKEND %q (want configmap, file, or etcd)", backend)
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"kops/metric_collector"
+)
+
+// FileBackend writes each sample as a JSON line to a daily-rotated file
+// under dir, so an operator without a ConfigMap/etcd dependency can still
+// inspect metrics offline (e.g. via "kubectl cp" off the pod's volume).
+type FileBackend struct {
+	dir string
+
+	mu     sync.Mutex
+	latest *metric_collector.MetricsData
+}
+
+// NewFileBackend returns a FileBackend rotating daily files under dir.
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+func (b *FileBackend) pathFor(t time.Time) string {
+	return filepath.Join(b.dir, fmt.Sprintf("metrics-%s.jsonl", t.UTC().Format("2006-01-02")))
+}
+
+func (b *FileBackend) AppendMetrics(ctx context.Context, m *metric_collector.MetricsData) error {
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %v", err)
+	}
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %v", err)
+	}
+
+	f, err := os.OpenFile(b.pathFor(time.Now()), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write metrics: %v", err)
+	}
+
+	b.mu.Lock()
+	latest := *m
+	b.latest = &latest
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *FileBackend) QueryRange(ctx context.Context, from, to time.Time) ([]metric_collector.MetricsData, error) {
+	var all []metric_collector.MetricsData
+
+	for day := from.UTC().Truncate(24 * time.Hour); !day.After(to); day = day.Add(24 * time.Hour) {
+		path := b.pathFor(day)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+			var m metric_collector.MetricsData
+			if err := json.Unmarshal([]byte(line), &m); err != nil {
+				continue
+			}
+			ts, err := time.Parse(time.RFC3339, m.Timestamp)
+			if err != nil {
+				continue
+			}
+			if !ts.Before(from) && !ts.After(to) {
+				all = append(all, m)
+			}
+		}
+	}
+
+	return all, nil
+}
+
+// Latest returns the most recently appended sample. It's tracked in memory
+// rather than read back from disk, since AppendMetrics already holds it.
+func (b *FileBackend) Latest(ctx context.Context) (*metric_collector.MetricsData, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.latest, nil
+}